@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// accessLogWriter records the status and size of the response, keeping the
+// streaming and websocket support of the wrapped writer.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection can't be hijacked")
+	}
+
+	// Switching protocols, the connection is taken over.
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// accessLog logs every request once served, with its method, path, status,
+// duration and client.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lw := &accessLogWriter{ResponseWriter: w}
+
+		next.ServeHTTP(lw, r)
+
+		user, _, _ := r.BasicAuth()
+		log.With("method", r.Method).
+			With("path", r.URL.Path).
+			With("status", lw.status).
+			With("size", lw.size).
+			With("duration", time.Since(start).Seconds()).
+			With("remote_addr", r.RemoteAddr).
+			With("user", user).
+			With("user_agent", r.UserAgent()).
+			Info("Served request")
+	})
+}
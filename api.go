@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+type lagResponse struct {
+	Clusters []exporter.ClusterLag `json:"clusters"`
+}
+
+// lagHandler serves the lag of the consumer groups as of the latest scrape,
+// optionally filtered by the cluster, group, topic and status query params,
+// each matching any of its values. The topic filter leaves out the other
+// partitions, though the totallag and maxlag of the groups are kept as is.
+func lagHandler(c *exporter.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		clusters, groups := r.Form["cluster"], r.Form["group"]
+		topics, statuses := r.Form["topic"], r.Form["status"]
+
+		resp := lagResponse{Clusters: []exporter.ClusterLag{}}
+
+		for _, cluster := range c.Lag() {
+			if !oneOf(clusters, cluster.Cluster) {
+				continue
+			}
+
+			// The clusters are shared, so they're filtered into copies.
+			filtered := cluster
+			filtered.Groups = []exporter.ConsumerGroupStatus{}
+
+			for _, group := range cluster.Groups {
+				if !oneOf(groups, group.Group) || !oneOf(statuses, group.Status) {
+					continue
+				}
+
+				if len(topics) > 0 {
+					var partitions []exporter.Partition
+					for _, partition := range group.Partitions {
+						if oneOf(topics, partition.Topic) {
+							partitions = append(partitions, partition)
+						}
+					}
+
+					if len(partitions) == 0 {
+						continue
+					}
+
+					group.Partitions = partitions
+				}
+
+				filtered.Groups = append(filtered.Groups, group)
+			}
+
+			resp.Clusters = append(resp.Clusters, filtered)
+		}
+
+		writeJSON(w, resp)
+	})
+}
+
+// problemSeverity ranks the statuses of the groups listed as problems.
+var problemSeverity = map[string]int{
+	"WARN":  1,
+	"ERR":   2,
+	"STALL": 2,
+}
+
+type problem struct {
+	Cluster         string               `json:"cluster"`
+	Group           string               `json:"group"`
+	Status          string               `json:"status"`
+	TotalLag        int64                `json:"totallag"`
+	WorstPartitions []exporter.Partition `json:"worst_partitions"`
+}
+
+type problemsResponse struct {
+	Problems []problem `json:"problems"`
+}
+
+// problemsHandler serves the consumer groups in the WARN, ERR or STALL
+// status as of the latest scrape, the most severe and lagging ones first,
+// optionally of the clusters in the cluster query params. Their partitions
+// which aren't OK are listed the most lagging first, up to the partitions
+// query param, 5 by default.
+func problemsHandler(c *exporter.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		maxPartitions := 5
+		if value := r.Form.Get("partitions"); value != "" {
+			var err error
+			if maxPartitions, err = strconv.Atoi(value); err != nil || maxPartitions < 0 {
+				http.Error(w, "Invalid partitions: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+
+		resp := problemsResponse{Problems: []problem{}}
+
+		for _, cluster := range c.Lag() {
+			if !oneOf(r.Form["cluster"], cluster.Cluster) {
+				continue
+			}
+
+			for _, group := range cluster.Groups {
+				if problemSeverity[group.Status] == 0 {
+					continue
+				}
+
+				worst := []exporter.Partition{}
+				for _, partition := range group.Partitions {
+					if partition.Status != "OK" {
+						worst = append(worst, partition)
+					}
+				}
+
+				sort.SliceStable(worst, func(i, j int) bool { return worst[i].CurrentLag > worst[j].CurrentLag })
+				if len(worst) > maxPartitions {
+					worst = worst[:maxPartitions]
+				}
+
+				resp.Problems = append(resp.Problems, problem{
+					Cluster:         cluster.Cluster,
+					Group:           group.Group,
+					Status:          group.Status,
+					TotalLag:        group.TotalLag,
+					WorstPartitions: worst,
+				})
+			}
+		}
+
+		sort.SliceStable(resp.Problems, func(i, j int) bool {
+			a, b := resp.Problems[i], resp.Problems[j]
+			if problemSeverity[a.Status] != problemSeverity[b.Status] {
+				return problemSeverity[a.Status] > problemSeverity[b.Status]
+			}
+
+			return a.TotalLag > b.TotalLag
+		})
+
+		writeJSON(w, resp)
+	})
+}
+
+// writeJSON writes the response of the API.
+func writeJSON(w http.ResponseWriter, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.With("err", err).Error("Failed writing the API response")
+	}
+}
+
+// oneOf tells whether value is any of values, or there are none.
+func oneOf(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
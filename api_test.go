@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+// scrapedCollector returns a collector which scraped the mock burrow
+// serving the fixture once.
+func scrapedCollector(t *testing.T, fixture *burrowtest.Fixture) *exporter.Collector {
+	t.Helper()
+
+	mock := burrowtest.NewServer(fixture)
+	t.Cleanup(mock.Close)
+
+	client := mock.Client(3)
+	t.Cleanup(client.Close)
+
+	c := exporter.NewCollector(client, "")
+	collect(c)
+
+	return c
+}
+
+// collect scrapes burrow with the collector, discarding the metrics.
+func collect(c *exporter.Collector) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	for range ch {
+	}
+}
+
+// getJSON serves the request with the handler, decoding its response.
+func getJSON(t *testing.T, handler http.Handler, target string, resp interface{}) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func TestLagHandler(t *testing.T) {
+	fixture := burrowtest.Synthetic(2, 2, 2)
+	fixture.Clusters["cluster-0"].Consumers["group-1"].Status = "WARN"
+
+	handler := lagHandler(scrapedCollector(t, fixture))
+
+	tests := []struct {
+		name   string
+		target string
+		// groups are the groups listed by cluster, along with their
+		// number of partitions.
+		groups map[string][]string
+	}{
+		{
+			name:   "all",
+			target: "/api/v1/lag",
+			groups: map[string][]string{"cluster-0": {"group-0 2", "group-1 2"}, "cluster-1": {"group-0 2", "group-1 2"}},
+		},
+		{
+			name:   "cluster",
+			target: "/api/v1/lag?cluster=cluster-1",
+			groups: map[string][]string{"cluster-1": {"group-0 2", "group-1 2"}},
+		},
+		{
+			name:   "groups",
+			target: "/api/v1/lag?group=group-1&group=group-2",
+			groups: map[string][]string{"cluster-0": {"group-1 2"}, "cluster-1": {"group-1 2"}},
+		},
+		{
+			name:   "topic",
+			target: "/api/v1/lag?cluster=cluster-0&topic=topic-0",
+			groups: map[string][]string{"cluster-0": {"group-0 2"}},
+		},
+		{
+			name:   "status",
+			target: "/api/v1/lag?status=WARN",
+			groups: map[string][]string{"cluster-0": {"group-1 2"}, "cluster-1": {}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var resp lagResponse
+			if code := getJSON(t, handler, test.target, &resp); code != http.StatusOK {
+				t.Fatalf("got status %d", code)
+			}
+
+			groups := make(map[string][]string)
+			for _, cluster := range resp.Clusters {
+				groups[cluster.Cluster] = []string{}
+				for _, group := range cluster.Groups {
+					groups[cluster.Cluster] = append(groups[cluster.Cluster], fmt.Sprintf("%v %d", group.Group, len(group.Partitions)))
+				}
+
+				sort.Strings(groups[cluster.Cluster])
+			}
+
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("got %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
+
+func TestProblemsHandler(t *testing.T) {
+	fixture := burrowtest.Synthetic(2, 4, 3)
+
+	// The total lag of group-N is 9N+3, its partitions lagging the more
+	// the higher their number.
+	setStatus := func(cluster, group, status string, partitions ...int) {
+		consumer := fixture.Clusters[cluster].Consumers[group]
+		consumer.Status = status
+		for _, p := range partitions {
+			consumer.Partitions[p].Status = status
+		}
+	}
+
+	setStatus("cluster-0", "group-1", "WARN", 0)
+	setStatus("cluster-0", "group-2", "ERR", 0, 1, 2)
+	setStatus("cluster-0", "group-3", "STALL", 1)
+	setStatus("cluster-1", "group-0", "WARN")
+
+	handler := problemsHandler(scrapedCollector(t, fixture))
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		// problems are the groups listed, with their worst partitions.
+		problems []string
+	}{
+		{
+			name:     "all",
+			target:   "/api/v1/problems",
+			code:     http.StatusOK,
+			problems: []string{"cluster-0/group-3 STALL [1]", "cluster-0/group-2 ERR [2 1 0]", "cluster-0/group-1 WARN [0]", "cluster-1/group-0 WARN []"},
+		},
+		{
+			name:     "cluster",
+			target:   "/api/v1/problems?cluster=cluster-1",
+			code:     http.StatusOK,
+			problems: []string{"cluster-1/group-0 WARN []"},
+		},
+		{
+			name:     "partitions",
+			target:   "/api/v1/problems?cluster=cluster-0&partitions=2",
+			code:     http.StatusOK,
+			problems: []string{"cluster-0/group-3 STALL [1]", "cluster-0/group-2 ERR [2 1]", "cluster-0/group-1 WARN [0]"},
+		},
+		{
+			name:   "invalid partitions",
+			target: "/api/v1/problems?partitions=-1",
+			code:   http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var resp problemsResponse
+			if code := getJSON(t, handler, test.target, &resp); code != test.code {
+				t.Fatalf("got status %d, want %d", code, test.code)
+			}
+
+			var problems []string
+			for _, problem := range resp.Problems {
+				var partitions []int32
+				for _, partition := range problem.WorstPartitions {
+					partitions = append(partitions, partition.Partition)
+				}
+
+				problems = append(problems, fmt.Sprintf("%v/%v %v %v", problem.Cluster, problem.Group, problem.Status, partitions))
+			}
+
+			if !reflect.DeepEqual(problems, test.problems) {
+				t.Errorf("got %v, want %v", problems, test.problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// runBench measures the refreshes of the collector, gathering its metrics
+// the given number of times.
+func runBench(c prometheus.Collector, iterations int) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "refresh\tduration\tallocs\tbytes\tseries\t")
+
+	var total time.Duration
+	for i := 1; i <= iterations; i++ {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+
+		families, err := registry.Gather()
+		if err != nil {
+			log.With("err", err).Error("Failed gathering the metrics")
+		}
+
+		took := time.Since(start)
+		runtime.ReadMemStats(&after)
+		total += took
+
+		series := 0
+		for _, family := range families {
+			series += len(family.Metric)
+		}
+
+		fmt.Fprintf(w, "%d\t%v\t%d\t%d\t%d\t\n", i, took, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc, series)
+	}
+
+	w.Flush()
+
+	if iterations > 0 {
+		fmt.Printf("average refresh duration: %v\n", total/time.Duration(iterations))
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// collectLevels are the values of the collect[] param, the level of detail
+// of the kafka_burrow_* metrics told by their most specific label, or the
+// exporter's own metrics.
+var collectLevels = map[string]bool{
+	"cluster":   true,
+	"group":     true,
+	"topic":     true,
+	"partition": true,
+	"exporter":  true,
+}
+
+// collectLevel returns the level of the metric, see collectLevels.
+func collectLevel(family *dto.MetricFamily, metric *dto.Metric) string {
+	if !strings.HasPrefix(family.GetName(), "kafka_burrow_") {
+		return "exporter"
+	}
+
+	level := "cluster"
+	for _, label := range metric.Label {
+		switch label.GetName() {
+		case "partition":
+			return "partition"
+		case "topic":
+			level = "topic"
+		case "group":
+			if level == "cluster" {
+				level = "group"
+			}
+		}
+	}
+
+	return level
+}
+
+// filteredMetricsHandler serves the metrics of the gatherer, narrowed down
+// by the collect[] params to the levels of detail, and by the cluster and
+// group params to the series with these labels, so different prometheus
+// jobs can pull different parts of them. The filters don't spare scraping
+// burrow, the whole of it is scraped anyway.
+func filteredMetricsHandler(gatherer prometheus.Gatherer, unfiltered http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		levels, clusters, groups := query["collect[]"], query["cluster"], query["group"]
+
+		if len(levels) == 0 && len(clusters) == 0 && len(groups) == 0 {
+			unfiltered.ServeHTTP(w, r)
+			return
+		}
+
+		for _, level := range levels {
+			if !collectLevels[level] {
+				http.Error(w, fmt.Sprintf("Invalid collect[] %q, expected one of cluster, group, topic, partition or exporter", level), http.StatusBadRequest)
+				return
+			}
+		}
+
+		filtered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			families, err := gatherer.Gather()
+
+			var kept []*dto.MetricFamily
+			for _, family := range families {
+				var metrics []*dto.Metric
+				for _, metric := range family.Metric {
+					if oneOf(levels, collectLevel(family, metric)) && labelOneOf(metric, "cluster", clusters) && labelOneOf(metric, "group", groups) {
+						metrics = append(metrics, metric)
+					}
+				}
+
+				if len(metrics) > 0 {
+					family.Metric = metrics
+					kept = append(kept, family)
+				}
+			}
+
+			return kept, err
+		})
+
+		promhttp.HandlerFor(filtered, metricsHandlerOpts()).ServeHTTP(w, r)
+	})
+}
+
+// metricsHandlerOpts are the options of the metrics handlers, serving
+// OpenMetrics to the scrapers negotiating it, as it carries the exemplars of
+// the lag histogram.
+func metricsHandlerOpts() promhttp.HandlerOpts {
+	return promhttp.HandlerOpts{
+		ErrorLog:          log.NewErrorLogger(),
+		EnableOpenMetrics: true,
+	}
+}
+
+// labelOneOf tells whether the metric's label is any of values, or there
+// are none. The metrics without the label are only kept when there are
+// none.
+func labelOneOf(metric *dto.Metric, name string, values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return oneOf(values, label.GetValue())
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// levelsRegistry registers a series of each level of detail, of two
+// clusters and groups.
+func levelsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	clusterLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "kafka_burrow_cluster_lag"}, []string{"cluster"})
+	totalLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "kafka_burrow_total_lag"}, []string{"cluster", "group"})
+	topicLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "kafka_burrow_topic_lag"}, []string{"cluster", "group", "topic"})
+	partitionLag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "kafka_burrow_partition_lag"}, []string{"cluster", "group", "topic", "partition"})
+	scrapes := prometheus.NewCounter(prometheus.CounterOpts{Name: "burrow_exporter_scrapes_total"})
+
+	registry.MustRegister(clusterLag, totalLag, topicLag, partitionLag, scrapes)
+	scrapes.Inc()
+
+	for _, cluster := range []string{"c0", "c1"} {
+		clusterLag.WithLabelValues(cluster).Set(1)
+
+		for _, group := range []string{"g0", "g1"} {
+			totalLag.WithLabelValues(cluster, group).Set(1)
+			topicLag.WithLabelValues(cluster, group, "t0").Set(1)
+			partitionLag.WithLabelValues(cluster, group, "t0", "0").Set(1)
+		}
+	}
+
+	return registry
+}
+
+func TestFilteredMetricsHandler(t *testing.T) {
+	unfiltered := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("unfiltered_metric 1\n"))
+	})
+
+	server := httptest.NewServer(filteredMetricsHandler(levelsRegistry(), unfiltered))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+		series []string
+	}{
+		{name: "unfiltered", status: http.StatusOK, series: []string{"unfiltered_metric{}"}},
+		{name: "invalid level", query: url.Values{"collect[]": {"consumer"}}, status: http.StatusBadRequest},
+		{
+			name:   "partitions",
+			query:  url.Values{"collect[]": {"partition"}},
+			status: http.StatusOK,
+			series: []string{
+				"kafka_burrow_partition_lag{c0,g0,0,t0}", "kafka_burrow_partition_lag{c0,g1,0,t0}",
+				"kafka_burrow_partition_lag{c1,g0,0,t0}", "kafka_burrow_partition_lag{c1,g1,0,t0}",
+			},
+		},
+		{
+			name:   "clusters and exporter",
+			query:  url.Values{"collect[]": {"cluster", "exporter"}},
+			status: http.StatusOK,
+			series: []string{"burrow_exporter_scrapes_total{}", "kafka_burrow_cluster_lag{c0}", "kafka_burrow_cluster_lag{c1}"},
+		},
+		{
+			name:   "cluster",
+			query:  url.Values{"cluster": {"c1"}},
+			status: http.StatusOK,
+			series: []string{
+				"kafka_burrow_cluster_lag{c1}",
+				"kafka_burrow_partition_lag{c1,g0,0,t0}", "kafka_burrow_partition_lag{c1,g1,0,t0}",
+				"kafka_burrow_topic_lag{c1,g0,t0}", "kafka_burrow_topic_lag{c1,g1,t0}",
+				"kafka_burrow_total_lag{c1,g0}", "kafka_burrow_total_lag{c1,g1}",
+			},
+		},
+		{
+			name:   "group topics",
+			query:  url.Values{"group": {"g0"}, "collect[]": {"topic"}},
+			status: http.StatusOK,
+			series: []string{"kafka_burrow_topic_lag{c0,g0,t0}", "kafka_burrow_topic_lag{c1,g0,t0}"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/metrics?" + test.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			var parser expfmt.TextParser
+			families, err := parser.TextToMetricFamilies(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var series []string
+			for name, family := range families {
+				for _, metric := range family.Metric {
+					var values []string
+					for _, label := range metric.Label {
+						values = append(values, label.GetValue())
+					}
+
+					series = append(series, name+"{"+strings.Join(values, ",")+"}")
+				}
+			}
+
+			sort.Strings(series)
+			if strings.Join(series, " ") != strings.Join(test.series, " ") {
+				t.Errorf("got series %v, want %v", series, test.series)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package exporter
+
+// GroupKey identifies a consumer group.
+type GroupKey struct {
+	Cluster string
+	Group   string
+}
+
+// TopicKey identifies a topic consumed by a consumer group.
+type TopicKey struct {
+	Cluster string
+	Group   string
+	Topic   string
+}
+
+// LagAggregate is the current lag of consumer groups summed up per
+// group, per topic of each group and per cluster.
+type LagAggregate struct {
+	Clusters map[string]int64
+	Groups   map[GroupKey]int64
+	Topics   map[TopicKey]int64
+}
+
+func NewLagAggregate() *LagAggregate {
+	return &LagAggregate{
+		Clusters: make(map[string]int64),
+		Groups:   make(map[GroupKey]int64),
+		Topics:   make(map[TopicKey]int64),
+	}
+}
+
+// Add sums up the partitions of a group evaluation, it should come from
+// the lag endpoint, as the status one only returns unhealthy partitions.
+func (a *LagAggregate) Add(status *ConsumerGroupStatus) {
+	group := GroupKey{Cluster: status.Cluster, Group: status.Group}
+
+	// Make sure groups without partitions show up with no lag.
+	a.Clusters[status.Cluster] += 0
+	a.Groups[group] += 0
+
+	for _, partition := range status.Partitions {
+		a.Clusters[status.Cluster] += partition.CurrentLag
+		a.Groups[group] += partition.CurrentLag
+		a.Topics[TopicKey{Cluster: status.Cluster, Group: status.Group, Topic: partition.Topic}] += partition.CurrentLag
+	}
+}
+
+// AggregateLag sums up the lag of the group evaluations in a single pass.
+func AggregateLag(statuses ...*ConsumerGroupStatus) *LagAggregate {
+	a := NewLagAggregate()
+
+	for _, status := range statuses {
+		a.Add(status)
+	}
+
+	return a
+}
+
+// AggregateClusterLag fetches the lag of every consumer group of the
+// cluster and sums it up, groups failing to be fetched are skipped.
+func (bc *BurrowClient) AggregateClusterLag(cluster string) (*LagAggregate, error) {
+	groups, err := bc.ListConsumers(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	a := NewLagAggregate()
+
+	for _, group := range groups.ConsumerGroups {
+		resp, err := bc.ConsumerGroupLag(cluster, group)
+		if err != nil {
+			continue
+		}
+
+		a.Add(&resp.Status)
+	}
+
+	return a, nil
+}
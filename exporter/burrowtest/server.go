@@ -0,0 +1,271 @@
+// Package burrowtest provides a mock burrow server, emulating the v2 and v3
+// HTTP API from fixture data, for testing code built on the exporter client.
+package burrowtest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/shamil/burrow_exporter/exporter"
+)
+
+// Fixture is the data served by the mock burrow.
+type Fixture struct {
+	Clusters map[string]*Cluster `json:"clusters"`
+}
+
+// Cluster is the data served for a single kafka cluster.
+type Cluster struct {
+	Details exporter.ClusterDetails `json:"details"`
+	// Topics maps topics to their partitions' log end offsets.
+	Topics map[string][]int64 `json:"topics"`
+	// Consumers maps consumer groups to their lag evaluation, the status
+	// endpoint only returns the partitions that aren't OK.
+	Consumers map[string]*exporter.ConsumerGroupStatus `json:"consumers"`
+}
+
+// LoadFixture reads a JSON encoded fixture from a file.
+func LoadFixture(path string) (*Fixture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fixture := &Fixture{}
+	if err := json.NewDecoder(f).Decode(fixture); err != nil {
+		return nil, err
+	}
+
+	return fixture, nil
+}
+
+// Server is a mock burrow, serving the API from its fixture.
+type Server struct {
+	*httptest.Server
+
+	mutex   sync.Mutex
+	fixture *Fixture
+}
+
+// NewServer starts a mock burrow serving the fixture, it should be closed
+// when done.
+func NewServer(fixture *Fixture) *Server {
+	s := &Server{fixture: fixture}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// SetFixture replaces the served data, e.g. to simulate a deleted group.
+func (s *Server) SetFixture(fixture *Fixture) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.fixture = fixture
+}
+
+// Client returns a client for the mock burrow, using the given API version.
+func (s *Server) Client(apiVersion int, opts ...exporter.ClientOption) *exporter.BurrowClient {
+	return exporter.NewBurrowClient([]string{s.URL}, apiVersion, opts...)
+}
+
+// clusterDetailsV2 and clusterDetailsV3 are the cluster details responses of
+// the API versions, the v2 one holds the cluster and the v3 one its module.
+type clusterDetailsV2 struct {
+	exporter.BurrowResp
+	Cluster exporter.ClusterDetails `json:"cluster"`
+}
+
+type clusterDetailsV3 struct {
+	exporter.BurrowResp
+	Module exporter.ClusterModule `json:"module"`
+}
+
+// module returns the v3 cluster module of the cluster details, whose
+// servers are the brokers along with their port.
+func (c *Cluster) module() exporter.ClusterModule {
+	module := exporter.ClusterModule{ClassName: "kafka", Servers: []string{}, TopicRefresh: 60, OffsetRefresh: 30}
+	for _, broker := range c.Details.Brokers {
+		if c.Details.BrokerPort != 0 {
+			broker = net.JoinHostPort(broker, strconv.Itoa(c.Details.BrokerPort))
+		}
+
+		module.Servers = append(module.Servers, broker)
+	}
+
+	return module
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusNotFound, exporter.BurrowResp{Error: true, Message: message})
+}
+
+func ok(message string) exporter.BurrowResp {
+	return exporter.BurrowResp{Message: message}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/burrow/admin" {
+		w.Write([]byte("GOOD"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "v2" && parts[0] != "v3") || parts[1] != "kafka" || r.Method != http.MethodGet {
+		notFound(w, "invalid request type")
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	version, parts := parts[0], parts[2:]
+	if len(parts) == 0 {
+		clusters := []string{}
+		for name := range s.fixture.Clusters {
+			clusters = append(clusters, name)
+		}
+
+		writeJSON(w, http.StatusOK, exporter.ClustersResp{BurrowResp: ok("cluster list returned"), Clusters: clusters})
+		return
+	}
+
+	cluster, found := s.fixture.Clusters[parts[0]]
+	if !found {
+		notFound(w, "cluster not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && version == "v2":
+		writeJSON(w, http.StatusOK, clusterDetailsV2{BurrowResp: ok("cluster detail returned"), Cluster: cluster.Details})
+
+	case len(parts) == 1:
+		writeJSON(w, http.StatusOK, clusterDetailsV3{BurrowResp: ok("cluster module detail returned"), Module: cluster.module()})
+
+	case len(parts) == 2 && parts[1] == "topic":
+		topics := []string{}
+		for name := range cluster.Topics {
+			topics = append(topics, name)
+		}
+
+		writeJSON(w, http.StatusOK, exporter.TopicsResp{BurrowResp: ok("topic list returned"), Topics: topics})
+
+	case len(parts) == 3 && parts[1] == "topic":
+		offsets, found := cluster.Topics[parts[2]]
+		if !found {
+			notFound(w, "topic not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, exporter.ClusterTopicDetailsResp{BurrowResp: ok("topic offsets returned"), Offsets: offsets})
+
+	case len(parts) == 2 && parts[1] == "consumer":
+		groups := []string{}
+		for name := range cluster.Consumers {
+			groups = append(groups, name)
+		}
+
+		writeJSON(w, http.StatusOK, exporter.ConsumerGroupsResp{BurrowResp: ok("consumer list returned"), ConsumerGroups: groups})
+
+	case len(parts) >= 3 && parts[1] == "consumer":
+		status, found := cluster.Consumers[parts[2]]
+		if !found {
+			notFound(w, "cluster or consumer not found")
+			return
+		}
+
+		s.serveConsumer(w, status, parts[3:])
+
+	default:
+		notFound(w, "invalid request type")
+	}
+}
+
+func (s *Server) serveConsumer(w http.ResponseWriter, status *exporter.ConsumerGroupStatus, parts []string) {
+	switch {
+	case len(parts) == 0:
+		details := exporter.ConsumerGroupDetailsResp{
+			BurrowResp: ok("consumer detail returned"),
+			Topics:     make(map[string][]exporter.ConsumerPartition),
+		}
+
+		for _, p := range status.Partitions {
+			partitions := details.Topics[p.Topic]
+			for int32(len(partitions)) <= p.Partition {
+				partitions = append(partitions, exporter.ConsumerPartition{})
+			}
+
+			start, end := p.Start, p.End
+			partitions[p.Partition] = exporter.ConsumerPartition{
+				Offsets:    []*exporter.Offset{&start, &end},
+				Owner:      p.Owner,
+				ClientID:   p.ClientID,
+				CurrentLag: p.CurrentLag,
+			}
+			details.Topics[p.Topic] = partitions
+		}
+
+		writeJSON(w, http.StatusOK, details)
+
+	case len(parts) == 1 && parts[0] == "lag":
+		writeJSON(w, http.StatusOK, exporter.ConsumerGroupStatusResp{BurrowResp: ok("consumer status returned"), Status: *status})
+
+	case len(parts) == 1 && parts[0] == "status":
+		filtered := *status
+		filtered.Partitions = nil
+
+		for _, p := range status.Partitions {
+			if p.Status != "OK" {
+				filtered.Partitions = append(filtered.Partitions, p)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, exporter.ConsumerGroupStatusResp{BurrowResp: ok("consumer status returned"), Status: filtered})
+
+	case len(parts) == 1 && parts[0] == "topic":
+		topics := []string{}
+		seen := make(map[string]bool)
+
+		for _, p := range status.Partitions {
+			if !seen[p.Topic] {
+				seen[p.Topic] = true
+				topics = append(topics, p.Topic)
+			}
+		}
+
+		writeJSON(w, http.StatusOK, exporter.TopicsResp{BurrowResp: ok("consumer topic list returned"), Topics: topics})
+
+	case len(parts) == 2 && parts[0] == "topic":
+		offsets := []int64{}
+
+		for _, p := range status.Partitions {
+			if p.Topic != parts[1] {
+				continue
+			}
+
+			for int32(len(offsets)) <= p.Partition {
+				offsets = append(offsets, 0)
+			}
+			offsets[p.Partition] = p.End.Offset
+		}
+
+		writeJSON(w, http.StatusOK, exporter.ConsumerGroupTopicDetailsResp{BurrowResp: ok("consumer topic detail returned"), Offsets: offsets})
+
+	default:
+		notFound(w, "invalid request type")
+	}
+}
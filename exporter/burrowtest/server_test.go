@@ -0,0 +1,69 @@
+package burrowtest_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		apiVersion int
+		brokers    []string
+	}{
+		{apiVersion: 2, brokers: []string{"broker-0", "broker-1", "broker-2"}},
+		{apiVersion: 3, brokers: []string{"broker-0:9092", "broker-1:9092", "broker-2:9092"}},
+	}
+
+	server := burrowtest.NewServer(burrowtest.Synthetic(2, 3, 4))
+	defer server.Close()
+
+	for _, test := range tests {
+		client := server.Client(test.apiVersion)
+		defer client.Close()
+
+		clusters, err := client.ListClusters()
+		if err != nil {
+			t.Fatalf("v%d: listing the clusters: %v", test.apiVersion, err)
+		}
+
+		sort.Strings(clusters.Clusters)
+		if want := []string{"cluster-0", "cluster-1"}; !reflect.DeepEqual(clusters.Clusters, want) {
+			t.Errorf("v%d: got clusters %v, want %v", test.apiVersion, clusters.Clusters, want)
+		}
+
+		details, err := client.ClusterDetails("cluster-0")
+		if err != nil {
+			t.Fatalf("v%d: getting the cluster details: %v", test.apiVersion, err)
+		}
+
+		if brokers := details.Brokers(); !reflect.DeepEqual(brokers, test.brokers) {
+			t.Errorf("v%d: got brokers %v, want %v", test.apiVersion, brokers, test.brokers)
+		}
+
+		consumers, err := client.ListConsumers("cluster-1")
+		if err != nil {
+			t.Fatalf("v%d: listing the consumers: %v", test.apiVersion, err)
+		}
+
+		if len(consumers.ConsumerGroups) != 3 {
+			t.Errorf("v%d: got consumers %v, want 3 of them", test.apiVersion, consumers.ConsumerGroups)
+		}
+
+		lag, err := client.ConsumerGroupLag("cluster-1", "group-2")
+		if err != nil {
+			t.Fatalf("v%d: getting the lag: %v", test.apiVersion, err)
+		}
+
+		// The lag of the synthetic partitions is their index across the groups.
+		if lag.Status.TotalLag != 8+9+10+11 || len(lag.Status.Partitions) != 4 {
+			t.Errorf("v%d: got totallag %d of %d partitions, want 38 of 4", test.apiVersion, lag.Status.TotalLag, len(lag.Status.Partitions))
+		}
+
+		if _, err := client.ConsumerGroupLag("cluster-1", "missing"); err == nil {
+			t.Errorf("v%d: got no error for a missing group", test.apiVersion)
+		}
+	}
+}
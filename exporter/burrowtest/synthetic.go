@@ -0,0 +1,73 @@
+package burrowtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shamil/burrow_exporter/exporter"
+)
+
+// Synthetic generates a fixture of the given size, each consumer group of
+// a cluster consumes its own topic with the given number of partitions.
+func Synthetic(clusters, groups, partitions int) *Fixture {
+	fixture := &Fixture{Clusters: make(map[string]*Cluster)}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	for c := 0; c < clusters; c++ {
+		cluster := &Cluster{
+			Details: exporter.ClusterDetails{
+				Brokers:      []string{"broker-0", "broker-1", "broker-2"},
+				BrokerPort:   9092,
+				OffsetsTopic: "__consumer_offsets",
+			},
+			Topics:    make(map[string][]int64),
+			Consumers: make(map[string]*exporter.ConsumerGroupStatus),
+		}
+
+		name := fmt.Sprintf("cluster-%d", c)
+
+		for g := 0; g < groups; g++ {
+			group, topic := fmt.Sprintf("group-%d", g), fmt.Sprintf("topic-%d", g)
+			status := &exporter.ConsumerGroupStatus{
+				Cluster:        name,
+				Group:          group,
+				Status:         "OK",
+				Complete:       1,
+				PartitionCount: partitions,
+			}
+
+			offsets := make([]int64, partitions)
+
+			for p := 0; p < partitions; p++ {
+				lag := int64((g*partitions + p) % 1000)
+				offsets[p] = 100000 + lag
+
+				partition := exporter.Partition{
+					Topic:      topic,
+					Partition:  int32(p),
+					Status:     "OK",
+					Start:      exporter.Offset{Offset: 90000, Timestamp: now - 600000, Lag: lag, MaxOffset: 90000 + lag},
+					End:        exporter.Offset{Offset: 100000, Timestamp: now, Lag: lag, MaxOffset: 100000 + lag},
+					CurrentLag: lag,
+					Complete:   1,
+					Owner:      fmt.Sprintf("host-%d", p%10),
+					ClientID:   fmt.Sprintf("client-%d", p%10),
+				}
+
+				status.Partitions = append(status.Partitions, partition)
+				status.TotalLag += lag
+
+				if lag > status.MaxLag.CurrentLag {
+					status.MaxLag = partition
+				}
+			}
+
+			cluster.Topics[topic] = offsets
+			cluster.Consumers[group] = status
+		}
+
+		fixture.Clusters[name] = cluster
+	}
+
+	return fixture
+}
@@ -0,0 +1,168 @@
+package exporter
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Expired entries are only dropped on access, sweep the leftovers (e.g.
+// deleted groups) every so often.
+const cacheSweepInterval = 5 * time.Minute
+
+// CacheTTLs are the times burrow responses are cached for, per kind of
+// endpoint, 0 disables caching of that kind.
+type CacheTTLs struct {
+	// Clusters is the TTL of the cluster list and details.
+	Clusters time.Duration
+	// Consumers is the TTL of the consumer group lists.
+	Consumers time.Duration
+	// Topics is the TTL of the topic lists.
+	Topics time.Duration
+	// TopicOffsets is the TTL of the topic partition offsets.
+	TopicOffsets time.Duration
+	// Status is the TTL of the consumer group status, lag and details.
+	Status time.Duration
+}
+
+func (t CacheTTLs) ttl(kind endpointKind) time.Duration {
+	switch kind {
+	case kindClusters:
+		return t.Clusters
+	case kindConsumers:
+		return t.Consumers
+	case kindTopics:
+		return t.Topics
+	case kindTopicOffsets:
+		return t.TopicOffsets
+	case kindStatus:
+		return t.Status
+	}
+
+	return 0
+}
+
+type cacheEntry struct {
+	body         []byte
+	etag         string
+	lastModified string
+	expiry       time.Time
+	used         time.Time
+}
+
+func (e cacheEntry) fresh(now time.Time) bool {
+	return now.Before(e.expiry)
+}
+
+// revalidatable tells whether the entry can be revalidated with a
+// conditional request once expired.
+func (e cacheEntry) revalidatable() bool {
+	return e.etag != "" || e.lastModified != ""
+}
+
+// responseCache holds raw response bodies keyed by endpoint. Expired
+// entries are kept while in use when they have validators, so they can be
+// revalidated with a conditional request.
+type responseCache struct {
+	mutex     sync.Mutex
+	entries   map[string]cacheEntry
+	lastSweep time.Time
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries:   make(map[string]cacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *responseCache) get(key string) (cacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+
+	now := time.Now()
+	if !entry.fresh(now) && !entry.revalidatable() {
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+
+	entry.used = now
+	c.entries[key] = entry
+
+	return entry, true
+}
+
+func (c *responseCache) set(key string, entry cacheEntry, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	entry.expiry = now.Add(ttl)
+	entry.used = now
+	c.entries[key] = entry
+
+	if now.Sub(c.lastSweep) < cacheSweepInterval {
+		return
+	}
+
+	for k, entry := range c.entries {
+		if entry.fresh(now) {
+			continue
+		}
+
+		if !entry.revalidatable() || now.Sub(entry.used) >= cacheSweepInterval {
+			delete(c.entries, k)
+		}
+	}
+
+	c.lastSweep = now
+}
+
+// expire drops the entry of the endpoint and those below it.
+func (c *responseCache) expire(endpoint string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.entries {
+		if key == endpoint || strings.HasPrefix(key, endpoint+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops the cached responses of the consumer group, or of the
+// whole cluster when group is empty, or all of them when cluster is empty
+// too.
+func (bc *BurrowClient) Invalidate(cluster, group string) {
+	endpoint := "/kafka"
+	if cluster != "" {
+		endpoint += "/" + cluster
+		if group != "" {
+			endpoint += "/consumer/" + group
+		}
+	}
+
+	bc.cache.expire(endpoint)
+}
+
+// WithCacheTTLs caches burrow responses, so slow and rarely changing
+// topology data isn't fetched on every scrape.
+func WithCacheTTLs(ttls CacheTTLs) ClientOption {
+	return func(bc *BurrowClient) {
+		bc.cacheTTLs = ttls
+	}
+}
+
+// WithConditionalRequests keeps the responses having an ETag or
+// Last-Modified validator, and revalidates them with If-None-Match and
+// If-Modified-Since requests, reusing the kept body on 304 Not Modified.
+func WithConditionalRequests() ClientOption {
+	return func(bc *BurrowClient) {
+		bc.conditionalRequests = true
+	}
+}
@@ -0,0 +1,172 @@
+package exporter_test
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+// countingBurrow passes the requests to the mock, counting them by path.
+type countingBurrow struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	requests map[string]int
+}
+
+func newCountingBurrow(handler http.Handler) *countingBurrow {
+	b := &countingBurrow{requests: make(map[string]int)}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mutex.Lock()
+		b.requests[r.URL.Path]++
+		b.mutex.Unlock()
+
+		handler.ServeHTTP(w, r)
+	}))
+
+	return b
+}
+
+func (b *countingBurrow) count(path string) int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.requests[path]
+}
+
+func TestClientCache(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(1, 1, 1))
+	defer mock.Close()
+
+	burrow := newCountingBurrow(mock.Config.Handler)
+	defer burrow.Close()
+
+	client := exporter.NewBurrowClient([]string{burrow.URL}, 3, exporter.WithCacheTTLs(exporter.CacheTTLs{
+		Clusters: time.Hour,
+		Status:   50 * time.Millisecond,
+	}))
+	defer client.Close()
+
+	tests := []struct {
+		name     string
+		call     func() error
+		path     string
+		requests int
+	}{
+		{name: "cluster list fetched", call: func() error { _, err := client.ListClusters(); return err }, path: "/v3/kafka", requests: 1},
+		{name: "cluster list cached", call: func() error { _, err := client.ListClusters(); return err }, path: "/v3/kafka", requests: 1},
+		{name: "consumers not cached", call: func() error { _, err := client.ListConsumers("cluster-0"); return err }, path: "/v3/kafka/cluster-0/consumer", requests: 1},
+		{name: "consumers fetched again", call: func() error { _, err := client.ListConsumers("cluster-0"); return err }, path: "/v3/kafka/cluster-0/consumer", requests: 2},
+		{name: "lag fetched", call: func() error { _, err := client.ConsumerGroupLag("cluster-0", "group-0"); return err }, path: "/v3/kafka/cluster-0/consumer/group-0/lag", requests: 1},
+		{name: "lag cached", call: func() error { _, err := client.ConsumerGroupLag("cluster-0", "group-0"); return err }, path: "/v3/kafka/cluster-0/consumer/group-0/lag", requests: 1},
+		{name: "lag expired", call: func() error {
+			time.Sleep(60 * time.Millisecond)
+			_, err := client.ConsumerGroupLag("cluster-0", "group-0")
+			return err
+		}, path: "/v3/kafka/cluster-0/consumer/group-0/lag", requests: 2},
+		{name: "cluster list invalidated", call: func() error {
+			client.Invalidate("", "")
+			_, err := client.ListClusters()
+			return err
+		}, path: "/v3/kafka", requests: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.call(); err != nil {
+				t.Fatal(err)
+			}
+
+			if requests := burrow.count(test.path); requests != test.requests {
+				t.Errorf("got %d requests to %v, want %d", requests, test.path, test.requests)
+			}
+		})
+	}
+}
+
+// etagBurrow serves the mock's responses with an ETag, answering the
+// requests for the current one with 304 Not Modified, counted.
+func etagBurrow(mock *burrowtest.Server, notModified *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		mock.Config.Handler.ServeHTTP(recorder, r)
+
+		etag := fmt.Sprintf(`"%x"`, sha1.Sum(recorder.Body.Bytes()))
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(recorder.Code)
+		w.Write(recorder.Body.Bytes())
+	})
+}
+
+func TestClientConditionalRequests(t *testing.T) {
+	fixture := burrowtest.Synthetic(1, 1, 1)
+
+	mock := burrowtest.NewServer(fixture)
+	defer mock.Close()
+
+	var notModified int32
+	burrow := newCountingBurrow(etagBurrow(mock, &notModified))
+	defer burrow.Close()
+
+	client := exporter.NewBurrowClient([]string{burrow.URL}, 3, exporter.WithConditionalRequests())
+	defer client.Close()
+
+	const path = "/v3/kafka/cluster-0/consumer/group-0/lag"
+
+	tests := []struct {
+		name string
+		// update changes the group's lag before the request.
+		update      bool
+		lag         int64
+		notModified int32
+	}{
+		{name: "fetched", lag: fixture.Clusters["cluster-0"].Consumers["group-0"].TotalLag},
+		{name: "not modified", lag: fixture.Clusters["cluster-0"].Consumers["group-0"].TotalLag, notModified: 1},
+		{name: "modified", update: true, lag: 42, notModified: 1},
+		{name: "not modified since", lag: 42, notModified: 2},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.update {
+				updated := burrowtest.Synthetic(1, 1, 1)
+				updated.Clusters["cluster-0"].Consumers["group-0"].TotalLag = 42
+				mock.SetFixture(updated)
+			}
+
+			resp, err := client.ConsumerGroupLag("cluster-0", "group-0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The body kept from the revalidated responses is reused.
+			if resp.Status.TotalLag != test.lag {
+				t.Errorf("got a total lag of %d, want %d", resp.Status.TotalLag, test.lag)
+			}
+
+			if requests := burrow.count(path); requests != i+1 {
+				t.Errorf("got %d requests, want %d", requests, i+1)
+			}
+
+			if n := atomic.LoadInt32(&notModified); n != test.notModified {
+				t.Errorf("got %d responses not modified, want %d", n, test.notModified)
+			}
+		})
+	}
+}
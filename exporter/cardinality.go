@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// Levels of detail of the metrics, the most detailed ones are dropped
+// first when there are too many series.
+const (
+	levelPartition = "partition"
+	levelTopic     = "topic"
+	levelOther     = "other"
+)
+
+// seriesInfo returns the level of detail of the metric, told by its
+// partition or topic label, and the number of series it's exposed as.
+func seriesInfo(metric prometheus.Metric) (string, int, error) {
+	m := &dto.Metric{}
+	if err := metric.Write(m); err != nil {
+		return "", 0, err
+	}
+
+	series := 1
+	if m.Histogram != nil {
+		// The buckets, +Inf included, the sum and the count.
+		series = len(m.Histogram.Bucket) + 3
+	}
+
+	level := levelOther
+	for _, label := range m.Label {
+		switch label.GetName() {
+		case "partition":
+			return levelPartition, series, nil
+		case "topic":
+			level = levelTopic
+		}
+	}
+
+	return level, series, nil
+}
+
+// limitSeries drops metrics so they don't exceed maxSeries series. The per
+// partition metrics are dropped first, then the per topic ones, and as a
+// last resort whatever exceeds the limit.
+func (c *Collector) limitSeries(metrics []prometheus.Metric) []prometheus.Metric {
+	levels := make([]string, len(metrics))
+	series := make([]int, len(metrics))
+	counts := make(map[string]int)
+	total := 0
+
+	for i, metric := range metrics {
+		level, n, err := seriesInfo(metric)
+		if err != nil {
+			log.With("err", err).Error("Failed to inspect metric")
+		}
+
+		levels[i], series[i] = level, n
+		counts[level] += n
+		total += n
+	}
+
+	if total <= c.maxSeries {
+		return metrics
+	}
+
+	dropped := make(map[string]bool)
+	var names []string
+
+	for _, level := range []string{levelPartition, levelTopic} {
+		if total <= c.maxSeries {
+			break
+		}
+
+		dropped[level] = true
+		names = append(names, level)
+		total -= counts[level]
+		c.droppedSeries.WithLabelValues(level).Add(float64(counts[level]))
+	}
+
+	log.Warnf("Exceeding the limit of %v series, dropping the %v metrics", c.maxSeries, strings.Join(names, " and "))
+
+	kept := metrics[:0]
+	exported := 0
+
+	for i, metric := range metrics {
+		if dropped[levels[i]] {
+			continue
+		}
+
+		if exported+series[i] > c.maxSeries {
+			c.droppedSeries.WithLabelValues(levels[i]).Add(float64(series[i]))
+			continue
+		}
+
+		exported += series[i]
+		kept = append(kept, metric)
+	}
+
+	return kept
+}
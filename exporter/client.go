@@ -1,12 +1,23 @@
 package exporter
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
+	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shamil/burrow_exporter/internal/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type BurrowResp struct {
@@ -27,9 +38,29 @@ type ClusterDetails struct {
 	OffsetsTopic  string   `json:"offsets_topic"`
 }
 
+// ClusterModule is the cluster module configuration returned by the v3 API
+// in place of ClusterDetails.
+type ClusterModule struct {
+	ClassName     string   `json:"class-name"`
+	Servers       []string `json:"servers"`
+	TopicRefresh  int      `json:"topic-refresh"`
+	OffsetRefresh int      `json:"offset-refresh"`
+}
+
 type ClusterDetailsResp struct {
 	BurrowResp
 	Cluster ClusterDetails `json:"cluster"`
+	Module  ClusterModule  `json:"module"`
+}
+
+// Brokers returns the cluster's brokers, whichever API version they came
+// from.
+func (r *ClusterDetailsResp) Brokers() []string {
+	if len(r.Module.Servers) > 0 {
+		return r.Module.Servers
+	}
+
+	return r.Cluster.Brokers
 }
 
 type ConsumerGroupsResp struct {
@@ -54,24 +85,76 @@ type Offset struct {
 	MaxOffset int64 `json:"max_offset"`
 }
 
+// Time returns the time the offset was committed at, burrow reports it in
+// milliseconds since the epoch. It's the zero time when there's no timestamp.
+func (o Offset) Time() time.Time {
+	if o.Timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, o.Timestamp*int64(time.Millisecond))
+}
+
+// AgeAt returns the time elapsed between the offset commit and now.
+func (o Offset) AgeAt(now time.Time) time.Duration {
+	return now.Sub(o.Time())
+}
+
+// Age returns the time elapsed since the offset was committed.
+func (o Offset) Age() time.Duration {
+	return o.AgeAt(time.Now())
+}
+
+// Completeness is how much of burrow's evaluation window is filled with
+// offsets, v3 reports it as a ratio while v2 only tells whether it's full.
+type Completeness float64
+
+// UnmarshalJSON implements json.Unmarshaler, accepting both forms.
+func (c *Completeness) UnmarshalJSON(data []byte) error {
+	var complete bool
+	if err := json.Unmarshal(data, &complete); err == nil {
+		*c = 0
+		if complete {
+			*c = 1
+		}
+		return nil
+	}
+
+	var ratio float64
+	if err := json.Unmarshal(data, &ratio); err != nil {
+		return err
+	}
+
+	*c = Completeness(ratio)
+
+	return nil
+}
+
 type ConsumerGroupStatus struct {
-	Cluster    string      `json:"cluster"`
-	Group      string      `json:"group"`
-	Status     string      `json:"status"`
-	MaxLag     Partition   `json:"maxlag"`
-	Partitions []Partition `json:"partitions"`
-	TotalLag   int64       `json:"totallag"`
-	Owner      string      `json:"owner"`
+	Cluster        string       `json:"cluster"`
+	Group          string       `json:"group"`
+	Status         string       `json:"status"`
+	Complete       Completeness `json:"complete"`
+	MaxLag         Partition    `json:"maxlag"`
+	Partitions     []Partition  `json:"partitions"`
+	PartitionCount int          `json:"partition_count"`
+	TotalLag       int64        `json:"totallag"`
+	Owner          string       `json:"owner"`
 }
 
+// Partition is the evaluation of a single partition. Start and End are the
+// first and last offsets of burrow's window, while CurrentLag (v3 only) is
+// the lag of the latest commit against the current log end offset.
 type Partition struct {
-	Topic      string `json:"topic"`
-	Partition  int32  `json:"partition"`
-	Status     string `json:"status"`
-	Start      Offset `json:"start"`
-	End        Offset `json:"end"`
-	CurrentLag int64  `json:"current_lag"`
-	Owner      string `json:"owner"`
+	Topic      string       `json:"topic"`
+	Partition  int32        `json:"partition"`
+	Status     string       `json:"status"`
+	Start      Offset       `json:"start"`
+	End        Offset       `json:"end"`
+	CurrentLag int64        `json:"current_lag"`
+	Complete   Completeness `json:"complete"`
+	Owner      string       `json:"owner"`
+	ClientID   string       `json:"client_id"`
 }
 
 type ConsumerGroupStatusResp struct {
@@ -79,19 +162,224 @@ type ConsumerGroupStatusResp struct {
 	Status ConsumerGroupStatus `json:"status"`
 }
 
+type ConsumerPartition struct {
+	Offsets    []*Offset `json:"offsets"`
+	Owner      string    `json:"owner"`
+	ClientID   string    `json:"client_id"`
+	CurrentLag int64     `json:"current-lag"`
+}
+
+type ConsumerGroupDetailsResp struct {
+	BurrowResp
+	Topics map[string][]ConsumerPartition `json:"topics"`
+}
+
 type ClusterTopicDetailsResp struct {
 	BurrowResp
 	Offsets []int64 `json:"offsets"`
 }
 
+type ConfigResp struct {
+	BurrowResp
+	General    map[string]interface{} `json:"general"`
+	Logging    map[string]interface{} `json:"logging"`
+	Zookeeper  map[string]interface{} `json:"zookeeper"`
+	HTTPServer map[string]interface{} `json:"httpserver"`
+}
+
+type ConfigModuleListResp struct {
+	BurrowResp
+	Coordinator string   `json:"coordinator"`
+	Modules     []string `json:"modules"`
+}
+
+type LogLevelResp struct {
+	BurrowResp
+	Level string `json:"level"`
+}
+
+type LogLevelReq struct {
+	Level string `json:"level"`
+}
+
+// endpointKind groups the burrow endpoints by the kind of data they
+// return, to tell apart cheap topology listings from group evaluations.
+type endpointKind int
+
+const (
+	kindClusters endpointKind = iota
+	kindConsumers
+	kindTopics
+	kindTopicOffsets
+	kindStatus
+	kindAdmin
+)
+
+func (k endpointKind) String() string {
+	switch k {
+	case kindClusters:
+		return "clusters"
+	case kindConsumers:
+		return "consumers"
+	case kindTopics:
+		return "topics"
+	case kindTopicOffsets:
+		return "topic-offsets"
+	case kindStatus:
+		return "status"
+	case kindAdmin:
+		return "admin"
+	}
+
+	return "unknown"
+}
+
+// Timeouts of the requests to burrow, per kind of endpoint, the listing
+// endpoints are cheap while the group status payloads can be huge.
+type Timeouts struct {
+	List        time.Duration
+	Status      time.Duration
+	HealthCheck time.Duration
+}
+
 type BurrowClient struct {
-	baseURL    string
+	baseURLs   []string
 	apiversion int
 	client     *http.Client
+	transport  *http.Transport
+	timeouts   Timeouts
+	cacheTTLs  CacheTTLs
+	cache      *responseCache
+	tracer     trace.Tracer
+
+	conditionalRequests bool
+
+	retryPolicy RetryPolicy
+	retryBudget *retryBudget
+
+	queue *requestQueue
+
+	retries          prometheus.Counter
+	retriesExhausted *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+
+	mutex  sync.Mutex
+	active int
+	// deadline bounds all the requests when set, e.g. to the deadline of
+	// the ongoing scrape, whose span is the parent of their spans.
+	deadline   time.Time
+	scrapeSpan trace.SpanContext
+
+	// ctx is the parent of all the requests, canceled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func (bc *BurrowClient) buildURL(endpoint string) (string, error) {
-	parsedUrl, err := url.Parse(bc.baseURL)
+var (
+	// ErrDeadlineExceeded is returned for the requests made after the
+	// deadline set with SetDeadline passed.
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+	// ErrClosed is returned for the requests made after Close.
+	ErrClosed = errors.New("client closed")
+)
+
+// Close cancels the in-flight requests to burrow, and fails all the
+// following ones with ErrClosed.
+func (bc *BurrowClient) Close() {
+	bc.cancel()
+	bc.transport.CloseIdleConnections()
+}
+
+// Interrupted returns why requests aren't made anymore, i.e. the deadline
+// passed or the client got closed, nil when they still are.
+func (bc *BurrowClient) Interrupted() error {
+	if bc.ctx.Err() != nil {
+		return ErrClosed
+	}
+
+	if bc.DeadlineExceeded() {
+		return ErrDeadlineExceeded
+	}
+
+	return nil
+}
+
+// SetDeadline bounds all the requests to burrow by the deadline, the zero
+// time removes it.
+func (bc *BurrowClient) SetDeadline(deadline time.Time) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.deadline = deadline
+}
+
+// DeadlineExceeded tells whether the deadline set with SetDeadline passed.
+func (bc *BurrowClient) DeadlineExceeded() bool {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return !bc.deadline.IsZero() && !time.Now().Before(bc.deadline)
+}
+
+// requestContext returns the context of a request with the timeout, ending
+// by the deadline at the latest. The parent must derive from the client's.
+func (bc *BurrowClient) requestContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	bc.mutex.Lock()
+	deadline := bc.deadline
+	bc.mutex.Unlock()
+
+	if !deadline.IsZero() && deadline.Before(time.Now().Add(timeout)) {
+		return context.WithDeadline(parent, deadline)
+	}
+
+	return context.WithTimeout(parent, timeout)
+}
+
+// BaseURLs returns all the configured Burrow base URLs, in failover order.
+func (bc *BurrowClient) BaseURLs() []string {
+	return bc.baseURLs
+}
+
+// RedactURL returns the URL with the password of its user info masked, to
+// be shown, logged or exported as a label.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return u.Redacted()
+}
+
+// ActiveURL returns the Burrow base URL requests are currently sent to.
+func (bc *BurrowClient) ActiveURL() string {
+	_, baseURL := bc.current()
+	return baseURL
+}
+
+func (bc *BurrowClient) current() (int, string) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	return bc.active, bc.baseURLs[bc.active]
+}
+
+// failover switches to the next base URL, unless another request
+// already moved away from the failed one.
+func (bc *BurrowClient) failover(failed int) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if len(bc.baseURLs) < 2 || bc.active != failed {
+		return
+	}
+
+	bc.active = (failed + 1) % len(bc.baseURLs)
+	log.Warnf("Failing over from %v to %v", RedactURL(bc.baseURLs[failed]), RedactURL(bc.baseURLs[bc.active]))
+}
+
+func (bc *BurrowClient) buildURL(baseURL, endpoint string) (string, error) {
+	parsedUrl, err := url.Parse(baseURL)
 	if err != nil {
 		return "", err
 	}
@@ -101,201 +389,601 @@ func (bc *BurrowClient) buildURL(endpoint string) (string, error) {
 	return parsedUrl.String(), nil
 }
 
-func (bc *BurrowClient) getJsonReq(endpoint string, dest interface{}) error {
-	resp, err := bc.client.Get(endpoint)
+func (bc *BurrowClient) timeout(kind endpointKind) time.Duration {
+	if kind == kindStatus {
+		return bc.timeouts.Status
+	}
+
+	return bc.timeouts.List
+}
+
+// bufferPool holds the buffers the cacheable responses are read into,
+// reused across the requests as most of them are of similar sizes.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// doJsonReq decodes the response into dest, and when cacheable returns it
+// as a cache entry if it may be cached (i.e. it's a successful response).
+// When a stale entry is given, the request is made conditional on it.
+func (bc *BurrowClient) doJsonReq(ctx context.Context, method, baseURL, endpoint string, kind endpointKind, body []byte, cacheable bool, stale *cacheEntry, dest interface{}) (*cacheEntry, error) {
+	endpoint, err := bc.buildURL(baseURL, fmt.Sprintf("/v%d%s", bc.apiversion, endpoint))
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	ctx, cancel := bc.requestContext(ctx, bc.timeout(kind))
+	defer cancel()
+
+	defer bc.observeRequest(kind.String(), time.Now())
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if stale != nil {
+		if stale.etag != "" {
+			req.Header.Set("If-None-Match", stale.etag)
+		}
+		if stale.lastModified != "" {
+			req.Header.Set("If-Modified-Since", stale.lastModified)
+		}
+	}
+
+	resp, err := bc.client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		return stale, json.Unmarshal(stale.body, dest)
+	}
+
+	// Decode the responses not kept for the cache straight off the wire,
+	// rather than reading the whole, possibly huge, body first.
+	if !cacheable {
+		return nil, json.NewDecoder(resp.Body).Decode(dest)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buf)
+
+	buf.Reset()
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), dest); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+
+	// The buffer gets reused, the cache needs its own copy.
+	return &cacheEntry{
+		body:         append([]byte(nil), buf.Bytes()...),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}, nil
+}
+
+// observeRequest records the duration of a request to burrow, including
+// reading its response, whether it succeeded or not.
+func (bc *BurrowClient) observeRequest(endpoint string, start time.Time) {
+	bc.requestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+}
+
+// jsonReq sends the request to the versioned API endpoint of the active
+// Burrow, failing over to the remaining base URLs in turn when it fails.
+func (bc *BurrowClient) jsonReq(method string, kind endpointKind, endpoint string, body interface{}, dest interface{}) (err error) {
+	ctx, span := bc.startSpan(method, kind, endpoint)
+	defer func() { endSpan(span, err) }()
+
+	var payload []byte
+
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	ttl := time.Duration(0)
+	if method == http.MethodGet {
+		ttl = bc.cacheTTLs.ttl(kind)
+	}
+
+	cacheable := method == http.MethodGet && (ttl > 0 || bc.conditionalRequests)
+
+	var stale *cacheEntry
+	if cacheable {
+		if entry, ok := bc.cache.get(endpoint); ok {
+			if entry.fresh(time.Now()) {
+				span.SetAttributes(attrCached.Bool(true))
+				return json.Unmarshal(entry.body, dest)
+			}
+
+			if bc.conditionalRequests && entry.revalidatable() {
+				stale = &entry
+			}
+		}
+	}
+
+	if err := bc.Interrupted(); err != nil {
 		return err
 	}
 
-	return nil
+	queueCtx, cancel := bc.requestContext(ctx, bc.timeout(kind))
+	err = bc.queue.acquire(queueCtx)
+	cancel()
+
+	if err != nil {
+		if err := bc.Interrupted(); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("no slot for the request to burrow (%v): %v", endpoint, err)
+	}
+	defer bc.queue.release()
+
+	bc.retryBudget.request()
+	backoff := bc.retryPolicy.Backoff
+	if limit := bc.retryPolicy.MaxBackoff; limit > 0 && backoff > limit {
+		backoff = limit
+	}
+
+	for retry := 0; ; retry++ {
+		var err error
+
+		for range bc.baseURLs {
+			idx, baseURL := bc.current()
+
+			var entry *cacheEntry
+			if entry, err = bc.doJsonReq(ctx, method, baseURL, endpoint, kind, payload, cacheable, stale, dest); err == nil {
+				if cacheable && entry != nil && (ttl > 0 || entry.revalidatable()) {
+					bc.cache.set(endpoint, *entry, ttl)
+				}
+				return nil
+			}
+
+			// Running out of time isn't burrow's fault, don't fail over.
+			if err := bc.Interrupted(); err != nil {
+				return err
+			}
+
+			log.With("err", err).Warnf("Request to burrow (%v) failed", RedactURL(baseURL))
+			bc.failover(idx)
+		}
+
+		if retry >= bc.retryPolicy.MaxRetries {
+			if bc.retryPolicy.MaxRetries > 0 {
+				bc.retriesExhausted.WithLabelValues("attempts").Inc()
+			}
+			return err
+		}
+
+		if !bc.retryBudget.allowRetry() {
+			bc.retriesExhausted.WithLabelValues("budget").Inc()
+			return err
+		}
+
+		bc.retries.Inc()
+		if err := bc.sleep(backoff); err != nil {
+			return err
+		}
+		backoff = bc.retryPolicy.nextBackoff(backoff)
+	}
+}
+
+func (bc *BurrowClient) getJsonReq(kind endpointKind, endpoint string, dest interface{}) error {
+	return bc.jsonReq(http.MethodGet, kind, endpoint, nil, dest)
 }
 
-func (bc *BurrowClient) HealthCheck() (bool, error) {
-	endpoint, err := bc.buildURL("/burrow/admin")
+func (bc *BurrowClient) postJsonReq(kind endpointKind, endpoint string, body interface{}, dest interface{}) error {
+	return bc.jsonReq(http.MethodPost, kind, endpoint, body, dest)
+}
+
+// HealthCheck checks the active Burrow's admin endpoint, failing over to
+// the next base URL when it's unhealthy.
+func (bc *BurrowClient) HealthCheck() (healthy bool, err error) {
+	ctx, span := bc.startSpan(http.MethodGet, kindAdmin, "/burrow/admin")
+	defer func() { endSpan(span, err) }()
+
+	idx, baseURL := bc.current()
+
+	endpoint, err := bc.buildURL(baseURL, "/burrow/admin")
 	if err != nil {
 		return false, err
 	}
 
-	if _, err := bc.client.Get(endpoint); err != nil {
+	ctx, cancel := bc.requestContext(ctx, bc.timeouts.HealthCheck)
+	defer cancel()
+
+	defer bc.observeRequest("health-check", time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
 		return false, err
 	}
 
+	resp, err := bc.client.Do(req)
+	if err != nil {
+		if err := bc.Interrupted(); err != nil {
+			return false, err
+		}
+
+		bc.failover(idx)
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bc.failover(idx)
+		return false, fmt.Errorf("unexpected status code from %v: %v", RedactURL(endpoint), resp.StatusCode)
+	}
+
 	return true, nil
 }
 
 func (bc *BurrowClient) ListClusters() (*ClustersResp, error) {
-	endpoint, err := bc.buildURL("/kafka")
-	if err != nil {
-		return nil, err
-	}
-
 	clusters := &ClustersResp{}
-	if err := bc.getJsonReq(endpoint, clusters); err != nil {
+	if err := bc.getJsonReq(kindClusters, "/kafka", clusters); err != nil {
 		return nil, err
 	}
 
 	if clusters.Error {
-		return nil, fmt.Errorf(clusters.Message)
+		return nil, errors.New(clusters.Message)
 	}
 
 	return clusters, nil
 }
 
 func (bc *BurrowClient) ClusterDetails(cluster string) (*ClusterDetailsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s", cluster))
-	if err != nil {
-		return nil, err
-	}
-
 	clusterDetails := &ClusterDetailsResp{}
-	if err := bc.getJsonReq(endpoint, clusterDetails); err != nil {
+	if err := bc.getJsonReq(kindClusters, fmt.Sprintf("/kafka/%s", cluster), clusterDetails); err != nil {
 		return nil, err
 	}
 
 	if clusterDetails.Error {
-		return nil, fmt.Errorf(clusterDetails.Message)
+		return nil, errors.New(clusterDetails.Message)
 	}
 
 	return clusterDetails, nil
 }
 
 func (bc *BurrowClient) ListConsumers(cluster string) (*ConsumerGroupsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/consumer", cluster))
-	if err != nil {
-		return nil, err
-	}
-
 	consumers := &ConsumerGroupsResp{}
-	if err := bc.getJsonReq(endpoint, consumers); err != nil {
+	if err := bc.getJsonReq(kindConsumers, fmt.Sprintf("/kafka/%s/consumer", cluster), consumers); err != nil {
 		return nil, err
 	}
 
 	if consumers.Error {
-		return nil, fmt.Errorf(consumers.Message)
+		return nil, errors.New(consumers.Message)
 	}
 
 	return consumers, nil
 }
 
 func (bc *BurrowClient) ListConsumerTopics(cluster, consumerGroup string) (*TopicsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/consumer/%s/topic", cluster, consumerGroup))
-	if err != nil {
-		return nil, err
-	}
-
 	consumerTopics := &TopicsResp{}
-	if err := bc.getJsonReq(endpoint, consumerTopics); err != nil {
+	if err := bc.getJsonReq(kindTopics, fmt.Sprintf("/kafka/%s/consumer/%s/topic", cluster, consumerGroup), consumerTopics); err != nil {
 		return nil, err
 	}
 
 	if consumerTopics.Error {
-		return nil, fmt.Errorf(consumerTopics.Message)
+		return nil, errors.New(consumerTopics.Message)
 	}
 
 	return consumerTopics, nil
 }
 
 func (bc *BurrowClient) ListTopics(cluster string) (*TopicsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/topic", cluster))
-	if err != nil {
-		return nil, err
-	}
-
 	consumerTopics := &TopicsResp{}
-	if err := bc.getJsonReq(endpoint, consumerTopics); err != nil {
+	if err := bc.getJsonReq(kindTopics, fmt.Sprintf("/kafka/%s/topic", cluster), consumerTopics); err != nil {
 		return nil, err
 	}
 
 	if consumerTopics.Error {
-		return nil, fmt.Errorf(consumerTopics.Message)
+		return nil, errors.New(consumerTopics.Message)
 	}
 
 	return consumerTopics, nil
 }
 
 func (bc *BurrowClient) ConsumerGroupTopicDetails(cluster, consumerGroup, topic string) (*ConsumerGroupTopicDetailsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/consumer/%s/topic/%s", cluster, consumerGroup, topic))
-	if err != nil {
-		return nil, err
-	}
-
 	topicDetails := &ConsumerGroupTopicDetailsResp{}
-	if err := bc.getJsonReq(endpoint, topicDetails); err != nil {
+	if err := bc.getJsonReq(kindStatus, fmt.Sprintf("/kafka/%s/consumer/%s/topic/%s", cluster, consumerGroup, topic), topicDetails); err != nil {
 		return nil, err
 	}
 
 	if topicDetails.Error {
-		return nil, fmt.Errorf(topicDetails.Message)
+		return nil, errors.New(topicDetails.Message)
 	}
 
 	return topicDetails, nil
 }
 
 func (bc *BurrowClient) ConsumerGroupStatus(cluster, consumerGroup string) (*ConsumerGroupStatusResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/consumer/%s/status", cluster, consumerGroup))
-	if err != nil {
+	status := &ConsumerGroupStatusResp{}
+	if err := bc.getJsonReq(kindStatus, fmt.Sprintf("/kafka/%s/consumer/%s/status", cluster, consumerGroup), status); err != nil {
 		return nil, err
 	}
 
+	if status.Error {
+		return nil, errors.New(status.Message)
+	}
+
+	return status, nil
+}
+
+func (bc *BurrowClient) ConsumerGroupLag(cluster, consumerGroup string) (*ConsumerGroupStatusResp, error) {
 	status := &ConsumerGroupStatusResp{}
-	if err := bc.getJsonReq(endpoint, status); err != nil {
+	if err := bc.getJsonReq(kindStatus, fmt.Sprintf("/kafka/%s/consumer/%s/lag", cluster, consumerGroup), status); err != nil {
 		return nil, err
 	}
 
 	if status.Error {
-		return nil, fmt.Errorf(status.Message)
+		return nil, errors.New(status.Message)
 	}
 
 	return status, nil
 }
 
-func (bc *BurrowClient) ConsumerGroupLag(cluster, consumerGroup string) (*ConsumerGroupStatusResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/consumer/%s/lag", cluster, consumerGroup))
-	if err != nil {
+// ConsumerGroupDetails returns the per partition offsets window, owner and
+// client id of a consumer group, it's only available in the v3 API.
+func (bc *BurrowClient) ConsumerGroupDetails(cluster, consumerGroup string) (*ConsumerGroupDetailsResp, error) {
+	details := &ConsumerGroupDetailsResp{}
+	if err := bc.getJsonReq(kindStatus, fmt.Sprintf("/kafka/%s/consumer/%s", cluster, consumerGroup), details); err != nil {
 		return nil, err
 	}
 
-	status := &ConsumerGroupStatusResp{}
-	if err := bc.getJsonReq(endpoint, status); err != nil {
+	if details.Error {
+		return nil, errors.New(details.Message)
+	}
+
+	return details, nil
+}
+
+// GetConfig returns the main configuration of burrow, it's only available
+// in the v3 API, as are the other config methods.
+func (bc *BurrowClient) GetConfig() (*ConfigResp, error) {
+	config := &ConfigResp{}
+	if err := bc.getJsonReq(kindAdmin, "/config", config); err != nil {
 		return nil, err
 	}
 
-	if status.Error {
-		return nil, fmt.Errorf(status.Message)
+	if config.Error {
+		return nil, errors.New(config.Message)
 	}
 
-	return status, nil
+	return config, nil
 }
 
-func (bc *BurrowClient) ClusterTopicDetails(cluster, topic string) (*ClusterTopicDetailsResp, error) {
-	endpoint, err := bc.buildURL(fmt.Sprintf("/kafka/%s/topic/%s", cluster, topic))
-	if err != nil {
+func (bc *BurrowClient) listConfigModules(coordinator string) (*ConfigModuleListResp, error) {
+	modules := &ConfigModuleListResp{}
+	if err := bc.getJsonReq(kindAdmin, fmt.Sprintf("/config/%s", coordinator), modules); err != nil {
 		return nil, err
 	}
 
+	if modules.Error {
+		return nil, errors.New(modules.Message)
+	}
+
+	return modules, nil
+}
+
+func (bc *BurrowClient) ListConsumersConfig() (*ConfigModuleListResp, error) {
+	return bc.listConfigModules("consumer")
+}
+
+func (bc *BurrowClient) ListClustersConfig() (*ConfigModuleListResp, error) {
+	return bc.listConfigModules("cluster")
+}
+
+func (bc *BurrowClient) ListEvaluatorsConfig() (*ConfigModuleListResp, error) {
+	return bc.listConfigModules("evaluator")
+}
+
+func (bc *BurrowClient) ListNotifiersConfig() (*ConfigModuleListResp, error) {
+	return bc.listConfigModules("notifier")
+}
+
+// GetLogLevel returns the current log level of burrow (v3 only).
+func (bc *BurrowClient) GetLogLevel() (*LogLevelResp, error) {
+	logLevel := &LogLevelResp{}
+	if err := bc.getJsonReq(kindAdmin, "/admin/loglevel", logLevel); err != nil {
+		return nil, err
+	}
+
+	if logLevel.Error {
+		return nil, errors.New(logLevel.Message)
+	}
+
+	return logLevel, nil
+}
+
+// SetLogLevel changes the log level of burrow (v3 only), one of: debug,
+// info, warn, error, panic or fatal.
+func (bc *BurrowClient) SetLogLevel(level string) (*BurrowResp, error) {
+	resp := &BurrowResp{}
+	if err := bc.postJsonReq(kindAdmin, "/admin/loglevel", &LogLevelReq{Level: level}, resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error {
+		return nil, errors.New(resp.Message)
+	}
+
+	return resp, nil
+}
+
+func (bc *BurrowClient) ClusterTopicDetails(cluster, topic string) (*ClusterTopicDetailsResp, error) {
 	topicDetails := &ClusterTopicDetailsResp{}
-	if err := bc.getJsonReq(endpoint, topicDetails); err != nil {
+	if err := bc.getJsonReq(kindTopicOffsets, fmt.Sprintf("/kafka/%s/topic/%s", cluster, topic), topicDetails); err != nil {
 		return nil, err
 	}
 
 	if topicDetails.Error {
-		return nil, fmt.Errorf(topicDetails.Message)
+		return nil, errors.New(topicDetails.Message)
 	}
 
 	return topicDetails, nil
 }
 
-func NewBurrowClient(baseUrl string, apiVersion int) *BurrowClient {
-	return &BurrowClient{
-		baseURL:    fmt.Sprintf("%s/v%d", baseUrl, apiVersion),
+// Describe implements prometheus.Collector.
+func (bc *BurrowClient) Describe(ch chan<- *prometheus.Desc) {
+	bc.queue.Describe(ch)
+	bc.retries.Describe(ch)
+	bc.retriesExhausted.Describe(ch)
+	bc.requestDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (bc *BurrowClient) Collect(ch chan<- prometheus.Metric) {
+	bc.queue.Collect(ch)
+	bc.retries.Collect(ch)
+	bc.retriesExhausted.Collect(ch)
+	bc.requestDuration.Collect(ch)
+}
+
+// ClientOption customizes a BurrowClient created by NewBurrowClient.
+type ClientOption func(*BurrowClient)
+
+// roundTripper returns the transport currently used by the http client,
+// so options can wrap it.
+func (bc *BurrowClient) roundTripper() http.RoundTripper {
+	if bc.client.Transport == nil {
+		return http.DefaultTransport
+	}
+
+	return bc.client.Transport
+}
+
+// WithTransportWrapper wraps the client transport with a custom one, e.g.
+// for instrumentation the exporter doesn't have built in. The requests are
+// traced with WithTracerProvider.
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) ClientOption {
+	return func(bc *BurrowClient) {
+		bc.client.Transport = wrap(bc.roundTripper())
+	}
+}
+
+// WithTimeouts overrides the default request timeouts, zero values keep
+// the default.
+func WithTimeouts(timeouts Timeouts) ClientOption {
+	return func(bc *BurrowClient) {
+		if timeouts.List > 0 {
+			bc.timeouts.List = timeouts.List
+		}
+		if timeouts.Status > 0 {
+			bc.timeouts.Status = timeouts.Status
+		}
+		if timeouts.HealthCheck > 0 {
+			bc.timeouts.HealthCheck = timeouts.HealthCheck
+		}
+	}
+}
+
+// TransportConfig tunes the http transport of the burrow requests, zero
+// values keep the defaults.
+type TransportConfig struct {
+	// DisableHTTP2 sticks to HTTP/1.1 over TLS, rather than attempting
+	// HTTP/2 by default.
+	DisableHTTP2          bool
+	TLSHandshakeTimeout   time.Duration
+	ExpectContinueTimeout time.Duration
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+}
+
+// WithTransportConfig applies the transport tuning, it must come before
+// options wrapping the transport.
+func WithTransportConfig(config TransportConfig) ClientOption {
+	return func(bc *BurrowClient) {
+		if config.DisableHTTP2 {
+			bc.transport.ForceAttemptHTTP2 = false
+			// A non-nil empty map is the documented way to disable HTTP/2.
+			bc.transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+
+		if config.TLSHandshakeTimeout > 0 {
+			bc.transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+		}
+		if config.ExpectContinueTimeout > 0 {
+			bc.transport.ExpectContinueTimeout = config.ExpectContinueTimeout
+		}
+
+		if config.MaxIdleConns > 0 {
+			bc.transport.MaxIdleConns = config.MaxIdleConns
+		}
+		if config.MaxIdleConnsPerHost > 0 {
+			bc.transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+		}
+		if config.MaxConnsPerHost > 0 {
+			bc.transport.MaxConnsPerHost = config.MaxConnsPerHost
+		}
+		if config.IdleConnTimeout > 0 {
+			bc.transport.IdleConnTimeout = config.IdleConnTimeout
+		}
+	}
+}
+
+func NewBurrowClient(baseUrls []string, apiVersion int, opts ...ClientOption) *BurrowClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bc := &BurrowClient{
+		ctx:        ctx,
+		cancel:     cancel,
+		baseURLs:   baseUrls,
 		apiversion: apiVersion,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
+		client:     &http.Client{Transport: transport},
+		transport:  transport,
+		timeouts: Timeouts{
+			List:        30 * time.Second,
+			Status:      30 * time.Second,
+			HealthCheck: 30 * time.Second,
 		},
-	}
+		tracer:      noop.NewTracerProvider().Tracer(tracerName),
+		cache:       newResponseCache(),
+		retryBudget: &retryBudget{},
+		queue:       newRequestQueue(),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "burrow_exporter_retries_total",
+			Help: "Total number of retried burrow requests.",
+		}),
+		retriesExhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "burrow_exporter_retries_exhausted_total",
+			Help: "Total number of failed burrow requests that weren't retried anymore, by reason (attempts or budget).",
+		}, []string{"reason"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "burrow_exporter_request_duration_seconds",
+			Help:    "Duration of the requests to burrow, by kind of endpoint.",
+			Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{"endpoint"}),
+	}
+
+	for _, opt := range opts {
+		opt(bc)
+	}
+
+	return bc
 }
@@ -1,13 +1,16 @@
 package exporter
 
 import (
+	"math"
+	"math/rand"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/common/log"
+	"github.com/shamil/burrow_exporter/internal/log"
 )
 
 // If we are missing a status, it will return 0
@@ -22,186 +25,786 @@ var Status = map[string]int{
 }
 
 var (
-	kafkaConsumerPartitionLagDesc           = prometheus.NewDesc("kafka_burrow_partition_lag", "The lag of the latest offset commit on a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionLagDesc           = prometheus.NewDesc("kafka_burrow_partition_lag", "The current lag of the latest offset commit on a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
 	kafkaConsumerPartitionCurrentOffsetDesc = prometheus.NewDesc("kafka_burrow_partition_current_offset", "The latest offset commit on a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
 	kafkaConsumerPartitionCurrentStatusDesc = prometheus.NewDesc("kafka_burrow_partition_status", "The status of a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
 	kafkaConsumerPartitionMaxOffsetDesc     = prometheus.NewDesc("kafka_burrow_partition_max_offset", "The log end offset on a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionStartTimeDesc     = prometheus.NewDesc("kafka_burrow_partition_start_timestamp_seconds", "The time of the first offset commit in burrow's evaluation window of a partition, in seconds since the epoch.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionEndTimeDesc       = prometheus.NewDesc("kafka_burrow_partition_end_timestamp_seconds", "The time of the latest offset commit in burrow's evaluation window of a partition, in seconds since the epoch.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionCommitAgeDesc     = prometheus.NewDesc("kafka_burrow_partition_last_commit_age_seconds", "The time elapsed since the latest offset commit on a partition as reported by burrow.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionOwnerDesc         = prometheus.NewDesc("kafka_burrow_partition_owner_info", "Info metric identifying the host and client owning a partition of the consumer group as reported by burrow (v3 only), always 1.", []string{"cluster", "group", "topic", "owner", "partition", "client_id"}, nil)
+	kafkaConsumerPartitionWindowDesc        = prometheus.NewDesc("kafka_burrow_partition_window_seconds", "The time span of burrow's evaluation window of a partition, from its first to its latest offset commit.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionWindowOffsetsDesc = prometheus.NewDesc("kafka_burrow_partition_window_offsets", "The number of offset commits in burrow's evaluation window of a partition.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerPartitionTimeLagDesc       = prometheus.NewDesc("kafka_burrow_partition_time_lag_seconds", "The estimated time the consumer is behind the head of a partition, its current lag divided by the produce rate over burrow's evaluation window.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerCommitAgeDesc              = prometheus.NewDesc("kafka_burrow_last_commit_age_seconds", "The time elapsed since the consumer group's latest offset commit on any of its partitions.", []string{"cluster", "group"}, nil)
+	kafkaConsumerMaxTimeLagDesc             = prometheus.NewDesc("kafka_burrow_max_time_lag_seconds", "The highest estimated time the consumer group is behind the head of any of its partitions.", []string{"cluster", "group"}, nil)
+	kafkaConsumerCatchUpDesc                = prometheus.NewDesc("kafka_burrow_catch_up_seconds", "The estimated time the consumer group needs to consume its lag, keeping the consume and produce rates over burrow's evaluation window, +Inf when it isn't catching up.", []string{"cluster", "group"}, nil)
 	kafkaConsumerTotalLagDesc               = prometheus.NewDesc("kafka_burrow_total_lag", "The total amount of lag for the consumer group as reported by burrow.", []string{"cluster", "group"}, nil)
+	kafkaConsumerMaxLagDesc                 = prometheus.NewDesc("kafka_burrow_max_lag", "The current lag of the consumer group's partition having the most lag as reported by burrow.", []string{"cluster", "group"}, nil)
+	kafkaConsumerLagVelocityDesc            = prometheus.NewDesc("kafka_burrow_lag_velocity", "The rate the total lag of the consumer group changed at since the previous scrape, in messages per second, positive when it's falling behind.", []string{"cluster", "group"}, nil)
+	kafkaConsumerMaxLagPartitionDesc        = prometheus.NewDesc("kafka_burrow_maxlag_partition", "Info metric identifying the consumer group's partition having the most lag, always 1.", []string{"cluster", "group", "topic", "owner", "partition"}, nil)
+	kafkaConsumerLagHistogramDesc           = prometheus.NewDesc("kafka_burrow_partition_lag_distribution", "The distribution of the current lag of the consumer group's partitions.", []string{"cluster", "group"}, nil)
+	kafkaConsumerTopicLagDesc               = prometheus.NewDesc("kafka_burrow_topic_lag", "The sum of the current lag of all the partitions of a topic consumed by the consumer group.", []string{"cluster", "group", "topic"}, nil)
+	kafkaClusterInfoDesc                    = prometheus.NewDesc("kafka_burrow_cluster_info", "Info metric describing the cluster as configured in burrow, always 1.", []string{"cluster", "offsets_topic", "broker_port", "zookeeper_port"}, nil)
+	kafkaClusterBrokersDesc                 = prometheus.NewDesc("kafka_burrow_cluster_brokers", "The number of brokers burrow connects to for the cluster.", []string{"cluster"}, nil)
+	kafkaClusterZookeepersDesc              = prometheus.NewDesc("kafka_burrow_cluster_zookeepers", "The number of zookeeper nodes burrow connects to for the cluster.", []string{"cluster"}, nil)
+	kafkaConsumerGroupsDesc                 = prometheus.NewDesc("kafka_burrow_consumer_groups", "The number of consumer groups of the cluster as reported by burrow.", []string{"cluster"}, nil)
+	kafkaClusterLagDesc                     = prometheus.NewDesc("kafka_burrow_cluster_lag", "The sum of the current lag of all the consumer groups of the cluster.", []string{"cluster"}, nil)
+	kafkaConsumerCompleteDesc               = prometheus.NewDesc("kafka_burrow_complete", "How much of burrow's evaluation window of the consumer group is filled with offsets, from 0 to 1, v2 only reports 0 or 1.", []string{"cluster", "group"}, nil)
 	kafkaConsumerStatusDesc                 = prometheus.NewDesc("kafka_burrow_status", "The status of a partition as reported by burrow.", []string{"cluster", "group"}, nil)
+	kafkaConsumerStalledPartitionsDesc      = prometheus.NewDesc("kafka_burrow_stalled_partitions", "The number of the consumer group's partitions having lag whose committed offset didn't advance over burrow's evaluation window.", []string{"cluster", "group"}, nil)
+	kafkaConsumerGroupStatusDesc            = prometheus.NewDesc("kafka_burrow_group_status", "Whether the consumer group is in the given status (1) or not (0) as reported by burrow.", []string{"cluster", "group", "status"}, nil)
+	kafkaConsumerPartitionStatusCountDesc   = prometheus.NewDesc("kafka_burrow_group_partitions", "The number of the consumer group's partitions in the given status as reported by burrow.", []string{"cluster", "group", "status"}, nil)
+	kafkaTopicsDesc                         = prometheus.NewDesc("kafka_burrow_topics", "The number of topics of the cluster as reported by burrow.", []string{"cluster"}, nil)
+	kafkaTopicPartitionsDesc                = prometheus.NewDesc("kafka_burrow_topic_partitions", "The number of partitions of a topic as reported by burrow.", []string{"cluster", "topic"}, nil)
 	kafkaTopicPartitionOffsetDesc           = prometheus.NewDesc("kafka_burrow_topic_partition_offset", "The latest offset on a topic's partition as reported by burrow.", []string{"cluster", "topic", "partition"}, nil)
+	kafkaBurrowEndpointActiveDesc           = prometheus.NewDesc("kafka_burrow_endpoint_active", "Whether the burrow endpoint is the one currently being scraped (1) or a failover standby (0).", []string{"endpoint"}, nil)
+	burrowUpDesc                            = prometheus.NewDesc("burrow_up", "Whether burrow could be reached (1) or not (0), i.e. its health check and cluster listing succeeded.", []string{"instance"}, nil)
+	scrapeDurationDesc                      = prometheus.NewDesc("burrow_exporter_scrape_duration_seconds", "The time it took to scrape burrow.", nil, nil)
+	degradedDesc                            = prometheus.NewDesc("burrow_exporter_degraded", "Whether the partition metrics are dropped as the memory used approaches the budget (1) or not (0).", nil, nil)
+	leaderDesc                              = prometheus.NewDesc("burrow_exporter_leader", "Whether this exporter replica holds the leader lease and scrapes burrow (1) or stands by (0).", nil, nil)
+	dataAgeDesc                             = prometheus.NewDesc("burrow_exporter_data_age_seconds", "The age of the cluster's exported metrics, i.e. the time since its last successful scrape.", []string{"cluster"}, nil)
+	refreshIntervalDesc                     = prometheus.NewDesc("burrow_exporter_refresh_interval_seconds", "The effective refresh interval of the cluster, including the stretching to its scrape duration.", []string{"cluster"}, nil)
 )
 
+// lagSample is the total lag of a consumer group seen at a given time.
+type lagSample struct {
+	lag int64
+	at  time.Time
+}
+
 type Collector struct {
 	client *BurrowClient
 	mutex  sync.Mutex
 
+	// lagSamples holds the total lag of each group seen on the previous
+	// scrape, to compute how fast it changes. The clusters are scraped
+	// concurrently, so they're guarded by their own mutex.
+	lagSamples   map[GroupKey]lagSample
+	samplesMutex sync.Mutex
+
+	scrapeErrors  *prometheus.CounterVec
+	groupsSkipped *prometheus.CounterVec
+
+	clusterLabels ClusterLabels
+
+	groupRules    RewriteRules
+	rawGroupLabel bool
+
+	topicRules  RewriteRules
+	tenantRules RewriteRules
+	topicFilter TopicFilter
+
+	// clusters are the only clusters scraped, all when empty.
+	clusters map[string]bool
+
+	// rules are the filter rules of the current scrape, reloaded from
+	// rulesFile when it's set.
+	rules     FilterRules
+	rulesFile *filterRulesFile
+
+	// refreshIntervals spaces the scrapes of each cluster, serving their
+	// snapshot in between.
+	refreshIntervals RefreshIntervals
+	snapshots        map[string]*clusterSnapshot
+	// restored is whether the snapshots were restored from the snapshot
+	// directory and not served yet.
+	restored bool
+
+	// scrapeJitter delays the start of each cluster's scrape by up to this
+	// long, spreading the requests to burrow.
+	scrapeJitter time.Duration
+	random       *rand.Rand
+
+	// incrementalRefresh only fetches the active groups again, except on
+	// every incrementalRefresh-th scrape of a cluster, 0 disables it.
+	incrementalRefresh int
+	maxIdleAge         time.Duration
+	groupCache         groupCache
+	groupsReused       *prometheus.CounterVec
+
+	// scrapeStart is the start of the ongoing scrape, lastHealthy and
+	// everHealthy the outcome of the scrapes, and standby whether another
+	// replica holds the lease, guarded by statusMutex as they're read while
+	// scraping.
+	statusMutex sync.Mutex
+	scrapeStart time.Time
+	lastHealthy bool
+	everHealthy bool
+	standby     bool
+	// lag is the lag of the consumer groups in the snapshots, also guarded
+	// by statusMutex, and subscriptions get the lag of the refreshed ones.
+	lag           []ClusterLag
+	subscriptions *subscriptions
+
+	// scrapedAt and healthy are the end and outcome of the last scrape,
+	// served again to the collections overlapping it.
+	scrapedAt      time.Time
+	healthy        bool
+	skippedScrapes prometheus.Counter
+
+	// leaseLock elects the replica scraping burrow, the others stand by.
+	leaseLock *LeaseLock
+
+	// shard is the part of the groups and topics scraped by this replica.
+	shard Shard
+
+	// memoryBudget is the memory in bytes the collector degrades to the
+	// group level metrics when approaching, 0 disables it.
+	memoryBudget uint64
+	degraded     bool
+
+	// snapshotDir is where the snapshots are saved to survive restarts.
+	snapshotDir string
+
+	// staleGrace is how long the previous snapshot of a cluster is served
+	// when scraping it fails, 0 disables it.
+	staleGrace time.Duration
+
+	// deadline bounds the next scrape, set from prometheus' scrape timeout.
+	deadlineMutex sync.Mutex
+	deadline      time.Time
+
+	// adaptiveRefresh stretches the refresh interval of each cluster to at
+	// least this many times its scrape duration, 0 disables it.
+	adaptiveRefresh float64
+
+	// topGroups limits the per partition metrics to the groups with the
+	// highest total lag of each cluster, when set.
+	topGroups int
+
+	// maxSeries limits the number of exported series, when set.
+	maxSeries     int
+	droppedSeries *prometheus.CounterVec
+
+	// skipIncomplete leaves out the lag metrics of the groups whose
+	// evaluation window isn't full.
+	skipIncomplete bool
+
+	// windowOffsets exports the number of offsets in the partitions'
+	// evaluation windows, which takes a request per group.
+	windowOffsets bool
+
+	// minPartitionLag is the lag a partition must have for its metrics to
+	// be exported.
+	minPartitionLag int64
+
+	// metricTimestamps sets the time of the consumer group samples to the
+	// latest offset commit burrow evaluated.
+	metricTimestamps bool
+
+	// lagBuckets are the upper bounds of the partition lag histogram, and
+	// lagNativeFactor the bucket growth factor of its native form, it's not
+	// exported when neither is set.
+	lagBuckets      []float64
+	lagNativeFactor float64
+
 	skipPartitionStatus        bool
 	skipConsumerStatus         bool
+	skipComplete               bool
+	skipGroupStatus            bool
+	skipPartitionStatusCount   bool
+	skipStalledPartitions      bool
 	skipPartitionLag           bool
 	skipPartitionCurrentOffset bool
 	skipPartitionMaxOffset     bool
+	skipPartitionTimestamp     bool
+	skipPartitionTimeLag       bool
+	skipPartitionCommitAge     bool
+	skipPartitionOwner         bool
+	skipPartitionWindow        bool
+	skipCommitAge              bool
+	skipMaxTimeLag             bool
+	skipCatchUp                bool
 	skipTotalLag               bool
+	skipMaxLag                 bool
+	skipLagVelocity            bool
+	skipMaxLagPartition        bool
+	skipTopicLag               bool
+	skipClusterLag             bool
+	skipConsumerGroups         bool
+	skipClusterInfo            bool
 	skipTopicPartitionOffset   bool
+	skipTopics                 bool
+	skipTopicPartitions        bool
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// withTimestamp sets the time of the metrics' samples, in place.
+func withTimestamp(t time.Time, metrics []prometheus.Metric) {
+	for i, metric := range metrics {
+		metrics[i] = prometheus.NewMetricWithTimestamp(t, metric)
+	}
 }
 
-func (c *Collector) processGroup(cluster, group string) (metrics []prometheus.Metric) {
-	resp, err := c.client.ConsumerGroupLag(cluster, group)
+// appendGauge creates a gauge and appends it to metrics, logging when it
+// can't be created.
+func appendGauge(metrics []prometheus.Metric, desc *prometheus.Desc, value float64, labels ...string) []prometheus.Metric {
+	metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, value, labels...)
 	if err != nil {
-		log.With("err", err).Errorf("Error getting lag for consumer group (%v)", group)
-		return
+		log.With("err", err).Errorf("Failed to create metric")
+		return metrics
+	}
+
+	return append(metrics, metric)
+}
+
+// topicLabels returns the name the topic is exported as and its tenant.
+func (c *Collector) topicLabels(topic string) (string, string) {
+	tenant, _ := c.tenantRules.Apply(topic)
+	return c.topicRules.Rewrite(topic), tenant
+}
+
+// withTenant adds the tenant label to the metrics of a topic, when topics
+// are mapped to tenants.
+func (c *Collector) withTenant(tenant string, metrics []prometheus.Metric) []prometheus.Metric {
+	if len(c.tenantRules) == 0 || len(metrics) == 0 {
+		return metrics
+	}
+
+	return collectAll(withLabels(prometheus.Labels{"tenant": tenant}, metricList(metrics)))
+}
+
+// matchTopic reports whether the topic's metrics are exported.
+func (c *Collector) matchTopic(topic string) bool {
+	return c.topicFilter.Match(topic) && c.rules.Topics.Match(topic)
+}
+
+// topicPartition identifies an exported partition, as rewritten topics may
+// end up with the same name.
+type topicPartition struct {
+	tenant    string
+	topic     string
+	partition int32
+}
+
+// processGroup exports the group's evaluation, the per partition metrics
+// are skipped unless detailed is set.
+func (c *Collector) processGroup(cluster, group string, resp *ConsumerGroupStatusResp, lag *LagAggregate, detailed bool) (metrics []prometheus.Metric) {
+	resp.Status.Group = c.groupRules.Rewrite(resp.Status.Group)
+	resp.Status.Partitions = filterPartitions(resp.Status.Partitions, c.matchTopic)
+
+	// The lag of incomplete evaluations may be off, so it can be left out.
+	incomplete := c.skipIncomplete && resp.Status.Complete < 1
+	if incomplete {
+		c.groupsSkipped.WithLabelValues("incomplete").Inc()
+		detailed = false
+	}
+
+	if incomplete {
+		// Still tell the group got exported, without accounting its lag.
+		lag.Groups[GroupKey{Cluster: resp.Status.Cluster, Group: resp.Status.Group}] += 0
+	} else {
+		lag.Add(&resp.Status)
 	}
 
+	key := GroupKey{Cluster: cluster, Group: group}
+	sample := lagSample{lag: resp.Status.TotalLag, at: time.Now()}
+	c.samplesMutex.Lock()
+	previous, seen := c.lagSamples[key]
+	c.lagSamples[key] = sample
+	c.samplesMutex.Unlock()
+
 	commonLabels := []string{resp.Status.Cluster, resp.Status.Group}
 
+	maxTimeLag, hasTimeLag := time.Duration(0), false
+	catchUp, catchingUp := time.Duration(0), true
+	lastCommit := time.Time{}
+	stalled := 0
+	now := time.Now()
+
+	// The number of offsets in the windows takes another request.
+	var details *ConsumerGroupDetailsResp
+	if c.windowOffsets && detailed {
+		var err error
+		if details, err = c.client.ConsumerGroupDetails(cluster, group); err != nil {
+			log.With("err", err).Errorf("Error getting details for consumer group (%v)", group)
+			c.scrapeErrors.WithLabelValues(cluster, "group-details").Inc()
+		}
+	}
+
+	exported := make(map[topicPartition]bool)
+
 	for _, partition := range resp.Status.Partitions {
+		timeLag, hasPartitionTimeLag := partition.EstimatedTimeLag()
+		if hasPartitionTimeLag {
+			hasTimeLag = true
+			if timeLag > maxTimeLag {
+				maxTimeLag = timeLag
+			}
+		}
 
-		labels := append(commonLabels, partition.Topic, partition.Owner, strconv.Itoa(int(partition.Partition)))
+		if partition.Stalled() {
+			stalled++
+		}
 
-		if !c.skipPartitionLag {
-			metric, err := prometheus.NewConstMetric(
-				kafkaConsumerPartitionLagDesc,
-				prometheus.GaugeValue,
-				float64(partition.CurrentLag),
-				labels...,
-			)
-
-			if err != nil {
-				log.With("err", err).Errorf("Failed to create metric")
-			} else {
-				metrics = append(metrics, metric)
+		if commit := partition.End.Time(); partition.End.Timestamp > 0 && commit.After(lastCommit) {
+			lastCommit = commit
+		}
+
+		// The group has caught up once its slowest partition has.
+		if eta, ok := partition.CatchUpTime(); !ok {
+			catchingUp = false
+		} else if eta > catchUp {
+			catchUp = eta
+		}
+
+		if !detailed || partition.CurrentLag < c.minPartitionLag {
+			continue
+		}
+
+		start := len(metrics)
+
+		topic, tenant := c.topicLabels(partition.Topic)
+		labels := append(commonLabels, topic, partition.Owner, strconv.Itoa(int(partition.Partition)))
+
+		if !c.skipPartitionOwner && (partition.Owner != "" || partition.ClientID != "") {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionOwnerDesc, 1, append(labels, partition.ClientID)...)
+		}
+
+		if !c.skipPartitionWindow && partition.Start.Timestamp > 0 && partition.End.Timestamp > 0 {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionWindowDesc, partition.WindowSeconds(), labels...)
+		}
+
+		if details != nil {
+			if partitions := details.Topics[partition.Topic]; int(partition.Partition) < len(partitions) {
+				metrics = appendGauge(metrics, kafkaConsumerPartitionWindowOffsetsDesc, float64(partitions[partition.Partition].WindowOffsets()), labels...)
 			}
 		}
 
+		if !c.skipPartitionTimeLag && hasPartitionTimeLag {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionTimeLagDesc, timeLag.Seconds(), labels...)
+		}
+
+		if !c.skipPartitionCommitAge && partition.End.Timestamp > 0 {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionCommitAgeDesc, partition.End.AgeAt(now).Seconds(), labels...)
+		}
+
+		if !c.skipPartitionLag {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionLagDesc, float64(partition.CurrentLag), labels...)
+		}
+
 		if !c.skipPartitionCurrentOffset {
-			metric, err := prometheus.NewConstMetric(
-				kafkaConsumerPartitionCurrentOffsetDesc,
-				prometheus.GaugeValue,
-				float64(partition.End.Offset),
-				labels...,
-			)
-
-			if err != nil {
-				log.With("err", err).Errorf("Failed to create metric")
-			} else {
-				metrics = append(metrics, metric)
-			}
+			metrics = appendGauge(metrics, kafkaConsumerPartitionCurrentOffsetDesc, float64(partition.End.Offset), labels...)
 		}
 
 		if !c.skipPartitionStatus {
-			metric, err := prometheus.NewConstMetric(
-				kafkaConsumerPartitionCurrentStatusDesc,
-				prometheus.GaugeValue,
-				float64(Status[partition.Status]),
-				labels...,
-			)
-
-			if err != nil {
-				log.With("err", err).Errorf("Failed to create metric")
-			} else {
-				metrics = append(metrics, metric)
-			}
+			metrics = appendGauge(metrics, kafkaConsumerPartitionCurrentStatusDesc, float64(Status[partition.Status]), labels...)
 		}
 
 		if !c.skipPartitionMaxOffset {
-			metric, err := prometheus.NewConstMetric(
-				kafkaConsumerPartitionMaxOffsetDesc,
-				prometheus.GaugeValue,
-				float64(partition.End.MaxOffset),
-				labels...,
-			)
-
-			if err != nil {
-				log.With("err", err).Errorf("Failed to create metric")
-			} else {
-				metrics = append(metrics, metric)
+			metrics = appendGauge(metrics, kafkaConsumerPartitionMaxOffsetDesc, float64(partition.End.MaxOffset), labels...)
+		}
+
+		if !c.skipPartitionTimestamp {
+			if partition.Start.Timestamp > 0 {
+				metrics = appendGauge(metrics, kafkaConsumerPartitionStartTimeDesc, unixSeconds(partition.Start.Time()), labels...)
+			}
+
+			if partition.End.Timestamp > 0 {
+				metrics = appendGauge(metrics, kafkaConsumerPartitionEndTimeDesc, unixSeconds(partition.End.Time()), labels...)
 			}
 		}
+
+		id := topicPartition{tenant: tenant, topic: topic, partition: partition.Partition}
+		if exported[id] {
+			log.Warnf("Topic (%v) of consumer group (%v) is rewritten to the already exported %v, skipping its partition metrics", partition.Topic, group, topic)
+			metrics = metrics[:start]
+			continue
+		}
+
+		exported[id] = true
+		metrics = append(metrics[:start], c.withTenant(tenant, metrics[start:])...)
+
+		if c.metricTimestamps && partition.End.Timestamp > 0 {
+			withTimestamp(partition.End.Time(), metrics[start:])
+		}
 	}
 
-	if !c.skipTotalLag {
-		metric, err := prometheus.NewConstMetric(
-			kafkaConsumerTotalLagDesc,
-			prometheus.GaugeValue,
-			float64(resp.Status.TotalLag),
-			commonLabels...,
-		)
+	groupStart := len(metrics)
 
-		if err != nil {
-			log.With("err", err).Errorf("Failed to create metric")
-		} else {
-			metrics = append(metrics, metric)
+	if !c.skipTotalLag && !incomplete {
+		metrics = appendGauge(metrics, kafkaConsumerTotalLagDesc, float64(resp.Status.TotalLag), commonLabels...)
+	}
+
+	if !c.skipMaxLag && !incomplete {
+		metrics = appendGauge(metrics, kafkaConsumerMaxLagDesc, float64(resp.Status.MaxLag.CurrentLag), commonLabels...)
+	}
+
+	if !c.skipLagVelocity && seen && !incomplete {
+		if elapsed := sample.at.Sub(previous.at).Seconds(); elapsed > 0 {
+			metrics = appendGauge(metrics, kafkaConsumerLagVelocityDesc, float64(sample.lag-previous.lag)/elapsed, commonLabels...)
 		}
 	}
 
+	if !c.skipMaxTimeLag && hasTimeLag && !incomplete {
+		metrics = appendGauge(metrics, kafkaConsumerMaxTimeLagDesc, maxTimeLag.Seconds(), commonLabels...)
+	}
+
+	if !c.skipCommitAge && !lastCommit.IsZero() {
+		metrics = appendGauge(metrics, kafkaConsumerCommitAgeDesc, now.Sub(lastCommit).Seconds(), commonLabels...)
+	}
+
+	if !c.skipCatchUp && !incomplete {
+		value := math.Inf(1)
+		if catchingUp {
+			value = catchUp.Seconds()
+		}
+
+		metrics = appendGauge(metrics, kafkaConsumerCatchUpDesc, value, commonLabels...)
+	}
+
+	if !c.skipMaxLagPartition && !incomplete && resp.Status.MaxLag.Topic != "" && c.matchTopic(resp.Status.MaxLag.Topic) {
+		maxLag := resp.Status.MaxLag
+		topic, tenant := c.topicLabels(maxLag.Topic)
+		labels := append(commonLabels, topic, maxLag.Owner, strconv.Itoa(int(maxLag.Partition)))
+
+		metrics = append(metrics, c.withTenant(tenant, appendGauge(nil, kafkaConsumerMaxLagPartitionDesc, 1, labels...))...)
+	}
+
 	if !c.skipConsumerStatus {
-		metric, err := prometheus.NewConstMetric(
-			kafkaConsumerStatusDesc,
-			prometheus.GaugeValue,
-			float64(Status[resp.Status.Status]),
-			commonLabels...,
-		)
+		metrics = appendGauge(metrics, kafkaConsumerStatusDesc, float64(Status[resp.Status.Status]), commonLabels...)
+	}
+
+	if !c.skipComplete {
+		metrics = appendGauge(metrics, kafkaConsumerCompleteDesc, float64(resp.Status.Complete), commonLabels...)
+	}
+
+	if (len(c.lagBuckets) > 0 || c.lagNativeFactor > 1) && !incomplete {
+		metrics = c.appendLagHistogram(metrics, resp.Status.Partitions, resp.Status.MaxLag, commonLabels...)
+	}
+
+	if !c.skipStalledPartitions {
+		metrics = appendGauge(metrics, kafkaConsumerStalledPartitionsDesc, float64(stalled), commonLabels...)
+	}
+
+	if !c.skipPartitionStatusCount {
+		counts := make(map[string]int)
+		for _, partition := range resp.Status.Partitions {
+			counts[partition.Status]++
+		}
+
+		for status := range Status {
+			metrics = appendGauge(metrics, kafkaConsumerPartitionStatusCountDesc, float64(counts[status]), append(commonLabels, status)...)
+		}
+	}
+
+	if !c.skipGroupStatus {
+		for status := range Status {
+			value := 0.0
+			if status == resp.Status.Status {
+				value = 1
+			}
+
+			metrics = appendGauge(metrics, kafkaConsumerGroupStatusDesc, value, append(commonLabels, status)...)
+		}
+	}
+
+	if c.metricTimestamps && !lastCommit.IsZero() {
+		withTimestamp(lastCommit, metrics[groupStart:])
+	}
+
+	if c.rawGroupLabel {
+		metrics = collectAll(withLabels(prometheus.Labels{"raw_group": group}, metricList(metrics)))
+	}
+
+	return metrics
+}
+
+// appendLagHistogram appends the histogram of the partitions' lag, with the
+// worst partition as the exemplar of its bucket.
+func (c *Collector) appendLagHistogram(metrics []prometheus.Metric, partitions []Partition, worst Partition, labels ...string) []prometheus.Metric {
+	exemplar := c.lagExemplar(worst)
 
+	if c.lagNativeFactor > 1 {
+		return append(metrics, c.nativeLagHistogram(partitions, worst, exemplar, labels...))
+	}
+
+	buckets := make(map[float64]uint64, len(c.lagBuckets))
+	sum := 0.0
+
+	for _, partition := range partitions {
+		lag := float64(partition.CurrentLag)
+		sum += lag
+
+		for _, bound := range c.lagBuckets {
+			if lag <= bound {
+				buckets[bound]++
+			}
+		}
+	}
+
+	metric, err := prometheus.NewConstHistogram(kafkaConsumerLagHistogramDesc, uint64(len(partitions)), sum, buckets, labels...)
+	if err != nil {
+		log.With("err", err).Errorf("Failed to create metric")
+		return metrics
+	}
+
+	if exemplar != nil {
+		withExemplar, err := prometheus.NewMetricWithExemplars(metric, prometheus.Exemplar{Value: float64(worst.CurrentLag), Labels: exemplar})
 		if err != nil {
-			log.With("err", err).Errorf("Failed to create metric")
+			log.With("err", err).Debug("Failed adding the exemplar of the worst partition")
 		} else {
-			metrics = append(metrics, metric)
+			metric = withExemplar
 		}
 	}
 
-	return metrics
+	return append(metrics, metric)
 }
 
 func (c *Collector) processTopic(cluster, topic string) (metrics []prometheus.Metric) {
 	details, err := c.client.ClusterTopicDetails(cluster, topic)
 	if err != nil {
 		log.With("err", err).Errorf("Error getting details for cluster topic (%v)", topic)
+		c.scrapeErrors.WithLabelValues(cluster, "topic-details").Inc()
 		return
 	}
 
-	if !c.skipTopicPartitionOffset {
+	topic, tenant := c.topicLabels(topic)
+
+	if !c.skipTopicPartitions {
+		metrics = appendGauge(metrics, kafkaTopicPartitionsDesc, float64(len(details.Offsets)), cluster, topic)
+	}
+
+	// The partition offsets are dropped along with the partition lag when
+	// degraded, as they're as many.
+	if !c.skipTopicPartitionOffset && !c.degraded {
 		for i, offset := range details.Offsets {
 			labels := []string{cluster, topic, strconv.Itoa(i)}
 
-			metric, err := prometheus.NewConstMetric(
-				kafkaTopicPartitionOffsetDesc,
-				prometheus.GaugeValue,
-				float64(offset),
-				labels...,
-			)
-
-			if err != nil {
-				log.With("err", err).Errorf("Failed to create metric")
-			} else {
-				metrics = append(metrics, metric)
-			}
+			metrics = appendGauge(metrics, kafkaTopicPartitionOffsetDesc, float64(offset), labels...)
 		}
 	}
 
-	return metrics
+	return c.withTenant(tenant, metrics)
+}
+
+// port formats a port as a label value, leaving it empty when unknown.
+func port(p int) string {
+	if p <= 0 {
+		return ""
+	}
+
+	return strconv.Itoa(p)
 }
 
-func (c *Collector) scrape(cluster string) (metrics []prometheus.Metric) {
-	groups, err := c.client.ListConsumers(cluster)
+func (c *Collector) processCluster(cluster string) (metrics []prometheus.Metric) {
+	details, err := c.client.ClusterDetails(cluster)
 	if err != nil {
-		log.With("err", err).Errorf("Error listing consumer groups (cluster: %v), skipping", cluster)
+		log.With("err", err).Errorf("Error getting details for cluster (%v)", cluster)
+		c.scrapeErrors.WithLabelValues(cluster, "cluster-details").Inc()
+		return
+	}
+
+	info := details.Cluster
+	metrics = appendGauge(metrics, kafkaClusterInfoDesc, 1, cluster, info.OffsetsTopic, port(info.BrokerPort), port(info.ZookeeperPort))
+	metrics = appendGauge(metrics, kafkaClusterBrokersDesc, float64(len(details.Brokers())), cluster)
+	metrics = appendGauge(metrics, kafkaClusterZookeepersDesc, float64(len(info.Zookeepers)), cluster)
+
+	return metrics
+}
+
+// mergeRewrittenGroups merges the evaluations of the groups rewritten to
+// the same name, unless they're told apart by the raw group label, so their
+// lag is summed up rather than exported twice with the same labels. The
+// merged group goes by the first of their raw names.
+func (c *Collector) mergeRewrittenGroups(groups []string, responses map[string]*ConsumerGroupStatusResp) []string {
+	if len(c.groupRules) == 0 || c.rawGroupLabel {
+		return groups
+	}
+
+	sorted := append([]string(nil), groups...)
+	sort.Strings(sorted)
+
+	merged := make([]string, 0, len(sorted))
+	first := make(map[string]string)
+
+	for _, group := range sorted {
+		name := c.groupRules.Rewrite(group)
+
+		into, ok := first[name]
+		if !ok {
+			first[name] = group
+			merged = append(merged, group)
+			continue
+		}
+
+		log.Debugf("Consumer group (%v) is rewritten to %v along with %v, merging them", group, name, into)
+		responses[into] = mergeGroupStatus(responses[into], responses[group])
+	}
+
+	return merged
+}
+
+// laggiestGroups returns the groups whose per partition metrics are
+// exported, the topGroups ones with the highest total lag, or all of them
+// when topGroups isn't set.
+func (c *Collector) laggiestGroups(groups []string, responses map[string]*ConsumerGroupStatusResp) map[string]bool {
+	ranked := append([]string(nil), groups...)
+
+	if c.topGroups > 0 && len(ranked) > c.topGroups {
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return responses[ranked[i]].Status.TotalLag > responses[ranked[j]].Status.TotalLag
+		})
+		ranked = ranked[:c.topGroups]
+	}
+
+	detailed := make(map[string]bool, len(ranked))
+	for _, group := range ranked {
+		detailed[group] = true
+	}
+
+	return detailed
+}
+
+// scrape returns the metrics and the lag of the consumer groups of the
+// cluster, and the error of listing its consumer groups, when the scrape
+// failed.
+func (c *Collector) scrape(cluster string) (metrics []prometheus.Metric, statuses []ConsumerGroupStatus, listErr error) {
+	// The cluster wide metrics are only exported by the shard owning the
+	// cluster.
+	ownsCluster := c.shard.owns("cluster", cluster)
+
+	if !c.skipClusterInfo && ownsCluster {
+		metrics = append(metrics, c.processCluster(cluster)...)
+	}
+
+	groups, listErr := c.client.ListConsumers(cluster)
+	if listErr != nil {
+		log.With("err", listErr).Errorf("Error listing consumer groups (cluster: %v), skipping", cluster)
+		c.scrapeErrors.WithLabelValues(cluster, "list-consumers").Inc()
 		groups = &ConsumerGroupsResp{}
+	} else if !c.skipConsumerGroups && ownsCluster {
+		metrics = appendGauge(metrics, kafkaConsumerGroupsDesc, float64(len(groups.ConsumerGroups)), cluster)
+	}
+
+	lag := NewLagAggregate()
+
+	var selected []string
+	responses := make(map[string]*ConsumerGroupStatusResp)
+
+	// Only the active groups are fetched again between the full refreshes.
+	var cached *clusterGroups
+	full := true
+	if c.incrementalRefresh > 0 {
+		cached = c.groupCache.forCluster(cluster)
+		cached.retain(groups.ConsumerGroups)
+		full = cached.cycle%c.incrementalRefresh == 0
 	}
 
 	for _, group := range groups.ConsumerGroups {
-		metrics = append(metrics, c.processGroup(cluster, group)...)
+		if err := c.client.Interrupted(); err != nil {
+			log.With("err", err).Warnf("Scrape interrupted (cluster: %v), skipping the remaining consumer groups", cluster)
+			if err == ErrDeadlineExceeded {
+				c.scrapeErrors.WithLabelValues(cluster, "deadline").Inc()
+			}
+			break
+		}
+
+		if !c.rules.Groups.Match(group) {
+			c.groupsSkipped.WithLabelValues("filter").Inc()
+			continue
+		}
+
+		if !c.shard.owns("group", cluster, group) {
+			c.groupsSkipped.WithLabelValues("shard").Inc()
+			continue
+		}
+
+		if !full {
+			if resp, ok := cached.reusable(group, time.Now(), c.maxIdleAge); ok {
+				c.groupsReused.WithLabelValues(cluster).Inc()
+				selected = append(selected, group)
+				responses[group] = resp
+				continue
+			}
+		}
+
+		resp, err := c.client.ConsumerGroupLag(cluster, group)
+		if err != nil {
+			log.With("err", err).Errorf("Error getting lag for consumer group (%v)", group)
+			c.scrapeErrors.WithLabelValues(cluster, "group-lag").Inc()
+			continue
+		}
+
+		if cached != nil {
+			cached.update(group, resp, time.Now())
+		}
+
+		selected = append(selected, group)
+		responses[group] = resp
+	}
+
+	selected = c.mergeRewrittenGroups(selected, responses)
+
+	detailed := c.laggiestGroups(selected, responses)
+	c.groupsSkipped.WithLabelValues("top-groups").Add(float64(len(selected) - len(detailed)))
+
+	if c.degraded {
+		detailed = nil
+	}
+
+	for _, group := range selected {
+		metrics = append(metrics, c.processGroup(cluster, group, responses[group], lag, detailed[group])...)
+		statuses = append(statuses, responses[group].Status)
+	}
+
+	if !c.skipTopicLag {
+		// Sum the lag again by the exported topic names, as different topics
+		// may be rewritten to the same one.
+		type tenantTopic struct {
+			TopicKey
+			tenant string
+		}
+
+		topicLag := make(map[tenantTopic]int64)
+		for key, value := range lag.Topics {
+			topic, tenant := c.topicLabels(key.Topic)
+			key.Topic = topic
+			topicLag[tenantTopic{TopicKey: key, tenant: tenant}] += value
+		}
+
+		for key, value := range topicLag {
+			metrics = append(metrics, c.withTenant(key.tenant, appendGauge(nil, kafkaConsumerTopicLagDesc, float64(value), key.Cluster, key.Group, key.Topic))...)
+		}
+	}
+
+	if !c.skipClusterLag {
+		metrics = appendGauge(metrics, kafkaClusterLagDesc, float64(lag.Clusters[cluster]), cluster)
 	}
 
 	topics, err := c.client.ListTopics(cluster)
 	if err != nil {
 		log.With("err", err).Errorf("Error listing topics (cluster: %v), skipping", cluster)
+		c.scrapeErrors.WithLabelValues(cluster, "list-topics").Inc()
 		topics = &TopicsResp{}
+	} else if !c.skipTopics && ownsCluster {
+		metrics = appendGauge(metrics, kafkaTopicsDesc, float64(len(topics.Topics)), cluster)
 	}
 
+	exported := make(map[topicPartition]bool)
+
 	for _, topic := range topics.Topics {
+		if !c.matchTopic(topic) || !c.shard.owns("topic", cluster, topic) {
+			continue
+		}
+
+		name, tenant := c.topicLabels(topic)
+
+		id := topicPartition{tenant: tenant, topic: name}
+		if exported[id] {
+			log.Warnf("Topic (%v) is rewritten to the already exported %v, skipping", topic, name)
+			continue
+		}
+
+		exported[id] = true
 		metrics = append(metrics, c.processTopic(cluster, topic)...)
 	}
 
-	return metrics
+	return metrics, statuses, listErr
 }
 
 // Describe implements prometheus.Collector.
@@ -213,42 +816,614 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	start := time.Now()
 	c.mutex.Lock()
+	c.setScrapeStart(start)
 
 	defer func() {
+		c.setScrapeStart(time.Time{})
 		c.mutex.Unlock()
 		log.Infof("Finished scraping burrow, took %v.", time.Now().Sub(start))
 	}()
 
+	// scraped is whether burrow is contacted, and healthy whether it
+	// answered.
+	healthy, scraped := true, true
+	defer func() { c.collectSelf(ch, start, healthy, scraped) }()
+	defer c.collectEndpoints(ch)
+
+	deadline := c.takeDeadline()
+
+	// Another scrape finished while this one waited for it, serve its
+	// metrics rather than scraping burrow again right away.
+	if c.scrapedAt.After(start) {
+		log.Info("Serving the metrics of the overlapping scrape...")
+		c.skippedScrapes.Inc()
+		healthy = c.healthy
+
+		var metrics []prometheus.Metric
+		for _, snapshot := range c.snapshots {
+			metrics = append(metrics, snapshot.metrics...)
+		}
+
+		c.send(ch, metrics)
+		return
+	}
+
+	// Serve the snapshots restored on startup right away rather than waiting
+	// for burrow, refreshing them in the background. They were saved from
+	// successful scrapes, though burrow's health is only known once it's
+	// scraped.
+	if c.restored {
+		log.Info("Serving the restored snapshots, refreshing them in the background...")
+		c.restored = false
+		scraped = false
+
+		var metrics []prometheus.Metric
+		for _, snapshot := range c.snapshots {
+			metrics = append(metrics, snapshot.metrics...)
+		}
+
+		c.publishLag(false)
+		c.send(ch, metrics)
+
+		// It waits for this collection to end.
+		go collectAll(c)
+		return
+	}
+
+	if c.leaseLock != nil {
+		standby := !c.leaseLock.acquire(time.Now())
+		c.setStandby(standby)
+
+		if standby {
+			scraped = false
+			return
+		}
+	}
+
 	log.Info("Scraping burrow...")
+
+	defer func() {
+		c.scrapedAt = time.Now()
+		c.healthy = healthy
+		c.setScrapeResult(healthy)
+	}()
+
+	span := c.client.startScrapeSpan()
+	defer func() { c.client.endScrapeSpan(span, healthy) }()
+
+	c.client.SetDeadline(deadline)
+	defer c.client.SetDeadline(time.Time{})
+
+	if _, err := c.client.HealthCheck(); err != nil {
+		log.With("err", err).Warn("Burrow health check failed")
+		c.scrapeErrors.WithLabelValues("", "health-check").Inc()
+		healthy = false
+	}
+
+	if c.rulesFile != nil {
+		c.rules = *c.rulesFile.current()
+	}
+
+	if c.memoryBudget > 0 {
+		c.checkMemory()
+	}
+
 	clusters, err := c.client.ListClusters()
 	if err != nil {
 		log.With("err", err).Error("Failed listing clusters")
+		c.scrapeErrors.WithLabelValues("", "list-clusters").Inc()
+		healthy = false
+
+		var metrics []prometheus.Metric
+		for cluster, snapshot := range c.snapshots {
+			if c.servesStale(cluster, snapshot, start) {
+				metrics = append(metrics, snapshot.metrics...)
+			}
+		}
+
+		c.send(ch, metrics)
 		return
 	}
 
-	for _, cluster := range clusters.Clusters {
-		for _, metric := range c.scrape(cluster) {
+	// Scrape the clusters concurrently, so the slowest one bounds the
+	// scrape duration rather than their sum.
+	results := make([][]prometheus.Metric, len(clusters.Clusters))
+	statuses := make([][]ConsumerGroupStatus, len(clusters.Clusters))
+	refreshed := make([]bool, len(clusters.Clusters))
+	took := make([]time.Duration, len(clusters.Clusters))
+	failed := make([]bool, len(clusters.Clusters))
+	skipped := make([]bool, len(clusters.Clusters))
+	var wg sync.WaitGroup
+
+	// Leave the scrapes at least half of the time left by the deadline.
+	maxDelay := c.scrapeJitter
+	if !deadline.IsZero() {
+		if left := time.Until(deadline) / 2; left < maxDelay {
+			maxDelay = left
+		}
+	}
+
+	for i, cluster := range clusters.Clusters {
+		if !c.rules.Clusters.Match(cluster) || (len(c.clusters) > 0 && !c.clusters[cluster]) {
+			skipped[i] = true
+			continue
+		}
+
+		if snapshot, ok := c.snapshots[cluster]; ok && start.Before(snapshot.due) {
+			results[i] = snapshot.metrics
+			continue
+		}
+
+		refreshed[i] = true
+		delay := c.jitter(maxDelay)
+
+		wg.Add(1)
+		go func(i int, cluster string) {
+			defer wg.Done()
+
+			if err := c.client.sleep(delay); err != nil {
+				failed[i] = true
+				return
+			}
+
+			scrapeStart := time.Now()
+			clusterMetrics, clusterStatuses, err := c.scrape(cluster)
+			took[i] = time.Since(scrapeStart)
+			failed[i] = err != nil
+
+			if len(c.clusterLabels) > 0 {
+				clusterMetrics = collectAll(withLabels(c.clusterLabels.forCluster(cluster), metricList(clusterMetrics)))
+			}
+
+			results[i] = clusterMetrics
+			statuses[i] = clusterStatuses
+		}(i, cluster)
+	}
+
+	wg.Wait()
+
+	// Keep the snapshots of the current clusters only.
+	snapshots := make(map[string]*clusterSnapshot)
+	var metrics []prometheus.Metric
+
+	for i, clusterMetrics := range results {
+		cluster := clusters.Clusters[i]
+
+		// The snapshots of the filtered out clusters are dropped, so their
+		// lag isn't served anymore either.
+		if skipped[i] {
+			continue
+		}
+
+		if snapshot, ok := c.snapshots[cluster]; ok && failed[i] && c.servesStale(cluster, snapshot, start) {
+			snapshots[cluster] = snapshot
+			metrics = append(metrics, snapshot.metrics...)
+			continue
+		}
+
+		if refreshed[i] {
+			// Skew the next refresh, so the clusters sharing an interval
+			// drift apart rather than being refreshed all at once. The
+			// failed clusters are due again right away, to be retried by
+			// the next scrape.
+			interval := c.refreshInterval(cluster, took[i])
+			due := start
+			if !failed[i] {
+				due = start.Add(interval - c.jitter(interval))
+			}
+
+			snapshots[cluster] = &clusterSnapshot{
+				metrics:  clusterMetrics,
+				groups:   statuses[i],
+				at:       start,
+				interval: interval,
+				due:      due,
+			}
+
+			if c.snapshotDir != "" && !failed[i] {
+				if err := saveSnapshot(c.snapshotDir, cluster, snapshots[cluster]); err != nil {
+					log.With("err", err).Errorf("Failed saving the snapshot of cluster %v", cluster)
+				}
+			}
+		} else if snapshot, ok := c.snapshots[cluster]; ok {
+			snapshots[cluster] = snapshot
+		}
+
+		metrics = append(metrics, clusterMetrics...)
+	}
+
+	c.snapshots = snapshots
+	c.publishLag(true)
+	c.send(ch, metrics)
+
+	// Forget the groups that are gone, so they don't get a bogus velocity
+	// if they come back later.
+	for key, sample := range c.lagSamples {
+		if snapshot, ok := c.snapshots[key.Cluster]; !ok || sample.at.Before(snapshot.at) {
+			delete(c.lagSamples, key)
+		}
+	}
+}
+
+// send sends the metrics, within the series limit.
+func (c *Collector) send(ch chan<- prometheus.Metric, metrics []prometheus.Metric) {
+	if c.maxSeries > 0 {
+		metrics = c.limitSeries(metrics)
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+}
+
+func (c *Collector) collectEndpoints(ch chan<- prometheus.Metric) {
+	active := c.client.ActiveURL()
+
+	for _, endpoint := range c.client.BaseURLs() {
+		value := 0.0
+		if endpoint == active {
+			value = 1
+		}
+
+		for _, metric := range appendGauge(nil, kafkaBurrowEndpointActiveDesc, value, RedactURL(endpoint)) {
 			ch <- metric
 		}
 	}
 }
 
-func NewCollector(burrowUrl string, apiVersion int, disabledMetrics string) *Collector {
+// collectSelf collects the metrics about the exporter's own scraping,
+// leaving burrow_up out when burrow wasn't scraped, e.g. while standing by.
+func (c *Collector) collectSelf(ch chan<- prometheus.Metric, start time.Time, healthy, scraped bool) {
+	var metrics []prometheus.Metric
+	if scraped {
+		up := 0.0
+		if healthy {
+			up = 1
+		}
+
+		metrics = appendGauge(metrics, burrowUpDesc, up, RedactURL(c.client.ActiveURL()))
+	}
+
+	metrics = appendGauge(metrics, scrapeDurationDesc, time.Since(start).Seconds())
+
+	if c.memoryBudget > 0 {
+		degraded := 0.0
+		if c.degraded {
+			degraded = 1
+		}
+
+		metrics = appendGauge(metrics, degradedDesc, degraded)
+	}
+
+	if c.leaseLock != nil {
+		leader := 0.0
+		if c.leaseLock.leader {
+			leader = 1
+		}
+
+		metrics = appendGauge(metrics, leaderDesc, leader)
+	}
+
+	for cluster, snapshot := range c.snapshots {
+		metrics = appendGauge(metrics, refreshIntervalDesc, snapshot.interval.Seconds(), cluster)
+		metrics = appendGauge(metrics, dataAgeDesc, start.Sub(snapshot.at).Seconds(), cluster)
+	}
+
+	for _, metric := range metrics {
+		ch <- metric
+	}
+
+	c.scrapeErrors.Collect(ch)
+	c.groupsSkipped.Collect(ch)
+	c.groupsReused.Collect(ch)
+	c.skippedScrapes.Collect(ch)
+	c.droppedSeries.Collect(ch)
+	c.subscriptions.dropped.Collect(ch)
+}
+
+// CollectorOption customizes a Collector created by NewCollector.
+type CollectorOption func(*Collector)
+
+// WithoutPartitionMetrics disables all the per partition metrics, only
+// exporting group and cluster level ones, to keep the cardinality down.
+func WithoutPartitionMetrics() CollectorOption {
+	return func(c *Collector) {
+		c.skipPartitionStatus = true
+		c.skipPartitionLag = true
+		c.skipPartitionCurrentOffset = true
+		c.skipPartitionMaxOffset = true
+		c.skipPartitionTimestamp = true
+		c.skipPartitionTimeLag = true
+		c.skipPartitionCommitAge = true
+		c.skipPartitionOwner = true
+		c.skipPartitionWindow = true
+		c.skipTopicPartitionOffset = true
+	}
+}
+
+// WithAggregateOnly only exports the per group totals (total lag, max lag
+// and status) and the per topic lag sums, skipping all partition detail.
+func WithAggregateOnly() CollectorOption {
+	return func(c *Collector) {
+		WithoutPartitionMetrics()(c)
+
+		c.skipTotalLag = false
+		c.skipMaxLag = false
+		c.skipTopicLag = false
+		c.skipConsumerStatus = false
+	}
+}
+
+// WithLagHistogram exports the distribution of the partitions' lag of each
+// group as a histogram with the given bucket upper bounds.
+func WithLagHistogram(buckets []float64) CollectorOption {
+	return func(c *Collector) {
+		c.lagBuckets = buckets
+	}
+}
+
+// WithNativeLagHistogram exports the distribution of the partitions' lag of
+// each group as a native histogram, whose bucket bounds grow by up to factor,
+// e.g. 1.1, along with the classic buckets of WithLagHistogram if any.
+func WithNativeLagHistogram(factor float64) CollectorOption {
+	return func(c *Collector) {
+		c.lagNativeFactor = factor
+	}
+}
+
+// WithClusterLabels adds the labels of each cluster to all its metrics.
+func WithClusterLabels(labels ClusterLabels) CollectorOption {
+	return func(c *Collector) {
+		c.clusterLabels = labels
+	}
+}
+
+// WithGroupRewrite rewrites the consumer group names with the rules, e.g.
+// to get a stable name out of groups embedding instance IDs. The original
+// name is added as the raw_group label when rawLabel is set, otherwise
+// the groups rewritten to the same name are merged into one.
+func WithGroupRewrite(rules RewriteRules, rawLabel bool) CollectorOption {
+	return func(c *Collector) {
+		c.groupRules = rules
+		c.rawGroupLabel = rawLabel
+	}
+}
+
+// WithTopicRewrite rewrites the topic names with the rules, e.g. to strip
+// prefixes. When tenant rules are given, a tenant label is added to the
+// topic metrics, set from the first tenant rule matching the topic name,
+// so per tenant topics rewritten to the same name can be told apart.
+func WithTopicRewrite(rules, tenantRules RewriteRules) CollectorOption {
+	return func(c *Collector) {
+		c.topicRules = rules
+		c.tenantRules = tenantRules
+	}
+}
+
+// WithTopicFilter only exports the metrics of the topics matching the
+// filter. The group totals, e.g. the total and max lag, are still the ones
+// burrow reports, including the filtered out topics.
+func WithTopicFilter(filter TopicFilter) CollectorOption {
+	return func(c *Collector) {
+		c.topicFilter = filter
+	}
+}
+
+// WithClusters only scrapes the given clusters.
+func WithClusters(clusters []string) CollectorOption {
+	return func(c *Collector) {
+		c.clusters = make(map[string]bool)
+		for _, cluster := range clusters {
+			c.clusters[cluster] = true
+		}
+	}
+}
+
+// WithFilterRulesFile loads filter rules from the YAML file, reloading it
+// whenever it changes, see LoadFilterRules.
+func WithFilterRulesFile(path string) (CollectorOption, error) {
+	file, err := newFilterRulesFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Collector) {
+		c.rulesFile = file
+		c.rules = *file.rules
+	}, nil
+}
+
+// WithTopGroups only exports the per partition metrics of the n groups
+// with the highest total lag of each cluster, the others only get their
+// group level metrics.
+func WithTopGroups(n int) CollectorOption {
+	return func(c *Collector) {
+		c.topGroups = n
+	}
+}
+
+// WithMaxSeries limits the number of exported series, dropping the per
+// partition metrics first, then the per topic ones when exceeded.
+func WithMaxSeries(n int) CollectorOption {
+	return func(c *Collector) {
+		c.maxSeries = n
+	}
+}
+
+// WithMinPartitionLag only exports the per partition metrics of the
+// partitions having at least this lag, the others are only accounted for
+// in the group and topic metrics.
+func WithMinPartitionLag(lag int64) CollectorOption {
+	return func(c *Collector) {
+		c.minPartitionLag = lag
+	}
+}
+
+// WithMetricTimestamps exports the consumer group samples with the time of
+// the latest offset commit burrow evaluated, rather than the scrape time,
+// so it's known how old the evaluation is.
+func WithMetricTimestamps() CollectorOption {
+	return func(c *Collector) {
+		c.metricTimestamps = true
+	}
+}
+
+// WithoutIncompleteLag leaves out the lag metrics of the consumer groups
+// whose burrow evaluation window isn't full yet, to avoid alerting on
+// partial evaluations. Their status is still exported.
+func WithoutIncompleteLag() CollectorOption {
+	return func(c *Collector) {
+		c.skipIncomplete = true
+	}
+}
+
+// WithWindowOffsets exports the number of offsets in the evaluation window
+// of each partition, fetching the details of every consumer group.
+func WithWindowOffsets() CollectorOption {
+	return func(c *Collector) {
+		c.windowOffsets = true
+	}
+}
+
+// WithRefreshIntervals only scrapes a cluster again once its refresh
+// interval elapsed, serving the metrics of its previous scrape until then.
+func WithRefreshIntervals(intervals RefreshIntervals) CollectorOption {
+	return func(c *Collector) {
+		c.refreshIntervals = intervals
+	}
+}
+
+// WithIncrementalRefresh reuses the previous status of the consumer groups
+// that didn't commit between their last two fetches, fetching all the groups
+// again on every fullEvery-th scrape of a cluster only. The lag of the idle
+// groups lags behind the producers in between, by up to maxIdleAge as they
+// are fetched again once their status is this old, 0 doesn't bound it.
+func WithIncrementalRefresh(fullEvery int, maxIdleAge time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.incrementalRefresh = fullEvery
+		c.maxIdleAge = maxIdleAge
+	}
+}
+
+// WithLeaseLock only scrapes burrow while holding the leader lease, the
+// standby replicas only export their own metrics and burrow's health.
+func WithLeaseLock(lock *LeaseLock) CollectorOption {
+	return func(c *Collector) {
+		c.leaseLock = lock
+	}
+}
+
+// WithShard only scrapes the consumer groups and topics of the shard, the
+// cluster wide metrics are exported by the shard owning the cluster. The
+// cluster and topic lag sums only cover the shard's groups, so they need to
+// be summed across the replicas.
+func WithShard(shard Shard) CollectorOption {
+	return func(c *Collector) {
+		c.shard = shard
+	}
+}
+
+// WithStaleGrace keeps serving the previous metrics of a cluster for up to
+// grace after its last successful scrape when scraping it fails, rather than
+// letting all its series vanish.
+func WithStaleGrace(grace time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.staleGrace = grace
+	}
+}
+
+// WithAdaptiveRefresh stretches the refresh interval of each cluster to at
+// least factor times the duration of its last scrape, e.g. 10 keeps burrow
+// busy with a cluster for at most a tenth of the time.
+func WithAdaptiveRefresh(factor float64) CollectorOption {
+	return func(c *Collector) {
+		c.adaptiveRefresh = factor
+	}
+}
+
+// WithScrapeJitter delays the start of each cluster's scrape by a random
+// duration up to jitter, and skews their refresh intervals by as much, so
+// burrow isn't hit by all the clusters at once. The delay is at most half
+// the time left by the scrape timeout. The groups of a cluster are fetched
+// one after the other already.
+func WithScrapeJitter(jitter time.Duration) CollectorOption {
+	return func(c *Collector) {
+		c.scrapeJitter = jitter
+	}
+}
+
+func NewCollector(client *BurrowClient, disabledMetrics string, opts ...CollectorOption) *Collector {
 	disabledMetricsSet := make(map[string]bool)
 
 	for _, v := range strings.Split(disabledMetrics, ",") {
 		disabledMetricsSet[v] = true
 	}
 
-	return &Collector{
-		client:                     NewBurrowClient(burrowUrl, apiVersion),
+	c := &Collector{
+		client:                     client,
+		lagSamples:                 make(map[GroupKey]lagSample),
+		random:                     newRandom(),
 		skipPartitionStatus:        disabledMetricsSet["partition-status"],
 		skipConsumerStatus:         disabledMetricsSet["consumer-status"],
+		skipComplete:               disabledMetricsSet["complete"],
+		skipGroupStatus:            disabledMetricsSet["group-status"],
+		skipPartitionStatusCount:   disabledMetricsSet["partition-status-count"],
+		skipStalledPartitions:      disabledMetricsSet["stalled-partitions"],
 		skipPartitionLag:           disabledMetricsSet["partition-lag"],
 		skipPartitionCurrentOffset: disabledMetricsSet["partition-current-offset"],
 		skipPartitionMaxOffset:     disabledMetricsSet["partition-max-offset"],
+		skipPartitionTimestamp:     disabledMetricsSet["partition-timestamp"],
+		skipPartitionTimeLag:       disabledMetricsSet["partition-time-lag"],
+		skipPartitionCommitAge:     disabledMetricsSet["partition-commit-age"],
+		skipPartitionOwner:         disabledMetricsSet["partition-owner"],
+		skipPartitionWindow:        disabledMetricsSet["partition-window"],
+		skipCommitAge:              disabledMetricsSet["commit-age"],
+		skipMaxTimeLag:             disabledMetricsSet["max-time-lag"],
+		skipCatchUp:                disabledMetricsSet["catch-up"],
 		skipTotalLag:               disabledMetricsSet["total-lag"],
+		skipMaxLag:                 disabledMetricsSet["max-lag"],
+		skipLagVelocity:            disabledMetricsSet["lag-velocity"],
+		skipMaxLagPartition:        disabledMetricsSet["max-lag-partition"],
+		skipTopicLag:               disabledMetricsSet["topic-lag"],
+		skipClusterLag:             disabledMetricsSet["cluster-lag"],
+		skipConsumerGroups:         disabledMetricsSet["consumer-groups"],
+		skipClusterInfo:            disabledMetricsSet["cluster-info"],
 		skipTopicPartitionOffset:   disabledMetricsSet["topic-partition-offset"],
+		skipTopics:                 disabledMetricsSet["topics"],
+		skipTopicPartitions:        disabledMetricsSet["topic-partitions"],
+		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "burrow_exporter_scrape_errors_total",
+			Help: "Total number of failed requests while scraping burrow, by cluster and stage of the scrape.",
+		}, []string{"cluster", "stage"}),
+		groupsSkipped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "burrow_exporter_groups_skipped_total",
+			Help: "Total number of consumer groups left out, entirely when filtered out or owned by another shard (filter, shard), or partly, skipping their partition metrics or lag (top-groups, incomplete).",
+		}, []string{"reason"}),
+		skippedScrapes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "burrow_exporter_skipped_scrapes_total",
+			Help: "Total number of collections served the metrics of the scrape they overlapped with, instead of scraping burrow again.",
+		}),
+		groupsReused: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "burrow_exporter_groups_reused_total",
+			Help: "Total number of consumer group statuses reused from the previous scrape instead of fetched, as the groups were idle.",
+		}, []string{"cluster"}),
+		droppedSeries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "burrow_exporter_dropped_series_total",
+			Help: "Total number of series dropped for exceeding the series limit, by level of detail (partition, topic or other).",
+		}, []string{"level"}),
+		subscriptions: newSubscriptions(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	// The restored snapshots are due like the scraped ones.
+	for cluster, snapshot := range c.snapshots {
+		snapshot.interval = c.refreshIntervals.interval(cluster)
+		snapshot.due = snapshot.at.Add(snapshot.interval)
+	}
+
+	return c
 }
 
@@ -0,0 +1,514 @@
+package exporter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+// failingBurrow passes the requests to the mock, but fails the consumer
+// listing of the cluster set failing.
+type failingBurrow struct {
+	*httptest.Server
+
+	mutex   sync.Mutex
+	cluster string
+}
+
+func newFailingBurrow(mock *burrowtest.Server) *failingBurrow {
+	f := &failingBurrow{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mutex.Lock()
+		failing := f.cluster != "" && strings.HasSuffix(r.URL.Path, "/kafka/"+f.cluster+"/consumer")
+		f.mutex.Unlock()
+
+		if failing {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+
+	return f
+}
+
+func (f *failingBurrow) fail(cluster string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.cluster = cluster
+}
+
+// unchecked hides the descriptions of the collector, so registering it
+// doesn't collect it.
+type unchecked struct {
+	prometheus.Collector
+}
+
+func (unchecked) Describe(chan<- *prometheus.Desc) {}
+
+// gather collects the metrics of the collector by name, once.
+func gather(t *testing.T, c *exporter.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(unchecked{c})
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gathering the metrics: %v", err)
+	}
+
+	byName := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+
+	return byName
+}
+
+// lagClusters returns the clusters with a total lag metric.
+func lagClusters(families map[string]*dto.MetricFamily) []string {
+	seen := make(map[string]bool)
+	for _, metric := range families["kafka_burrow_total_lag"].GetMetric() {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "cluster" {
+				seen[label.GetValue()] = true
+			}
+		}
+	}
+
+	return sortedKeys(seen)
+}
+
+// servedClusters returns the clusters served by Lag.
+func servedClusters(c *exporter.Collector) []string {
+	seen := make(map[string]bool)
+	for _, cluster := range c.Lag() {
+		seen[cluster.Cluster] = true
+	}
+
+	return sortedKeys(seen)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestCollectFilteredCluster(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(2, 2, 1))
+	defer mock.Close()
+
+	rulesPath := filepath.Join(t.TempDir(), "rules.yml")
+	if err := os.WriteFile(rulesPath, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := exporter.WithFilterRulesFile(rulesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := mock.Client(3)
+	defer client.Close()
+
+	// The clusters aren't due again, so the filtered out one would be
+	// carried over if its snapshot was kept.
+	c := exporter.NewCollector(client, "", rules, exporter.WithRefreshIntervals(exporter.RefreshIntervals{Default: time.Hour}))
+
+	if clusters := lagClusters(gather(t, c)); !equal(clusters, []string{"cluster-0", "cluster-1"}) {
+		t.Fatalf("got the lag of clusters %v, want both", clusters)
+	}
+
+	if err := os.WriteFile(rulesPath, []byte("clusters:\n  exclude: cluster-1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ReloadRules(); err != nil {
+		t.Fatal(err)
+	}
+
+	if clusters := lagClusters(gather(t, c)); !equal(clusters, []string{"cluster-0"}) {
+		t.Errorf("got the lag of clusters %v, want cluster-0 only", clusters)
+	}
+
+	if clusters := servedClusters(c); !equal(clusters, []string{"cluster-0"}) {
+		t.Errorf("served the lag of clusters %v, want cluster-0 only", clusters)
+	}
+}
+
+func TestCollectStaleGrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace time.Duration
+		// failing is the clusters with a total lag while cluster-1 fails.
+		failing []string
+	}{
+		{name: "without grace", failing: []string{"cluster-0"}},
+		{name: "with grace", grace: time.Hour, failing: []string{"cluster-0", "cluster-1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock := burrowtest.NewServer(burrowtest.Synthetic(2, 2, 1))
+			defer mock.Close()
+
+			burrow := newFailingBurrow(mock)
+			defer burrow.Close()
+
+			client := exporter.NewBurrowClient([]string{burrow.URL}, 3)
+			defer client.Close()
+
+			c := exporter.NewCollector(client, "", exporter.WithStaleGrace(test.grace))
+
+			if clusters := lagClusters(gather(t, c)); !equal(clusters, []string{"cluster-0", "cluster-1"}) {
+				t.Fatalf("got the lag of clusters %v, want both", clusters)
+			}
+
+			burrow.fail("cluster-1")
+
+			if clusters := lagClusters(gather(t, c)); !equal(clusters, test.failing) {
+				t.Errorf("got the lag of clusters %v while failing, want %v", clusters, test.failing)
+			}
+		})
+	}
+}
+
+func TestCollectRetriesFailedCluster(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(2, 2, 1))
+	defer mock.Close()
+
+	burrow := newFailingBurrow(mock)
+	defer burrow.Close()
+
+	client := exporter.NewBurrowClient([]string{burrow.URL}, 3)
+	defer client.Close()
+
+	c := exporter.NewCollector(client, "", exporter.WithRefreshIntervals(exporter.RefreshIntervals{Default: time.Hour}))
+
+	burrow.fail("cluster-1")
+
+	if clusters := lagClusters(gather(t, c)); !equal(clusters, []string{"cluster-0"}) {
+		t.Fatalf("got the lag of clusters %v while failing, want cluster-0 only", clusters)
+	}
+
+	// The failed cluster is retried by the next scrape rather than once
+	// due, while the other one is served from its snapshot.
+	burrow.fail("")
+
+	if clusters := lagClusters(gather(t, c)); !equal(clusters, []string{"cluster-0", "cluster-1"}) {
+		t.Errorf("got the lag of clusters %v once recovered, want both", clusters)
+	}
+}
+
+func TestCollectStandby(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(1, 2, 1))
+	defer mock.Close()
+
+	path := filepath.Join(t.TempDir(), "lease")
+
+	collector := func(identity string) *exporter.Collector {
+		client := mock.Client(3)
+		t.Cleanup(client.Close)
+
+		return exporter.NewCollector(client, "", exporter.WithLeaseLock(&exporter.LeaseLock{Path: path, Identity: identity, Duration: time.Minute}))
+	}
+
+	tests := []struct {
+		name      string
+		collector *exporter.Collector
+		leader    bool
+	}{
+		{name: "leader", collector: collector("a"), leader: true},
+		{name: "standby", collector: collector("b"), leader: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			families := gather(t, test.collector)
+
+			if leader := families["burrow_exporter_leader"].GetMetric()[0].GetGauge().GetValue() == 1; leader != test.leader {
+				t.Errorf("got leader %v, want %v", leader, test.leader)
+			}
+
+			// The standby doesn't scrape burrow, so it mustn't report it up,
+			// nor a successful scrape.
+			if _, up := families["burrow_up"]; up != test.leader {
+				t.Errorf("got burrow_up reported %v, want %v", up, test.leader)
+			}
+
+			if clusters := lagClusters(families); (len(clusters) > 0) != test.leader {
+				t.Errorf("got the lag of clusters %v", clusters)
+			}
+
+			if standby := test.collector.Standby(); standby == test.leader {
+				t.Errorf("got standby %v, want %v", standby, !test.leader)
+			}
+
+			if ready := test.collector.Ready(false); ready != test.leader {
+				t.Errorf("got ready %v, want %v", ready, test.leader)
+			}
+		})
+	}
+}
+
+func TestCollectRestoredSnapshots(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(2, 2, 1))
+	defer mock.Close()
+
+	dir := t.TempDir()
+
+	snapshots, err := exporter.WithSnapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := mock.Client(3)
+	defer client.Close()
+
+	if clusters := lagClusters(gather(t, exporter.NewCollector(client, "", snapshots))); len(clusters) != 2 {
+		t.Fatalf("got the lag of clusters %v, want both", clusters)
+	}
+
+	// The restarted exporter can't reach burrow, so its restored snapshots
+	// are all it has.
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	restored, err := exporter.WithSnapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downClient := exporter.NewBurrowClient([]string{down.URL}, 3)
+	defer downClient.Close()
+
+	c := exporter.NewCollector(downClient, "", restored)
+	sub := c.Subscribe()
+	defer sub.Close()
+
+	families := gather(t, c)
+
+	if clusters := lagClusters(families); len(clusters) != 2 {
+		t.Errorf("got the restored lag of clusters %v, want both", clusters)
+	}
+
+	// Burrow's health is unknown until the background scrape.
+	if _, up := families["burrow_up"]; up {
+		t.Error("reported burrow_up for the restored snapshots")
+	}
+
+	if c.Ready(false) {
+		t.Error("ready before scraping burrow")
+	}
+
+	if clusters := servedClusters(c); len(clusters) != 2 {
+		t.Errorf("served the restored lag of clusters %v, want both", clusters)
+	}
+
+	// It waits for the background scrape, which fails.
+	if up := gather(t, c)["burrow_up"].GetMetric(); len(up) != 1 || up[0].GetGauge().GetValue() != 0 {
+		t.Errorf("got burrow_up %v, want 0", up)
+	}
+
+	// The restored lag isn't fresh, so the subscribers aren't sent it.
+	if len(sub.Updates) > 0 {
+		t.Errorf("got %d updates of the restored lag", len(sub.Updates))
+	}
+}
+
+func TestCollectRewrittenGroups(t *testing.T) {
+	fixture := burrowtest.Synthetic(1, 3, 2)
+
+	var want float64
+	for _, status := range fixture.Clusters["cluster-0"].Consumers {
+		want += float64(status.TotalLag)
+	}
+
+	mock := burrowtest.NewServer(fixture)
+	defer mock.Close()
+
+	client := mock.Client(3)
+	defer client.Close()
+
+	rules, err := exporter.ParseRewriteRules([]string{`group-[0-9]+=group`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := exporter.NewCollector(client, "", exporter.WithGroupRewrite(rules, false))
+
+	// The groups rewritten to the same name are summed up rather than
+	// exported once.
+	lag := gather(t, c)["kafka_burrow_total_lag"].GetMetric()
+	if len(lag) != 1 {
+		t.Fatalf("got %d total lag series, want 1", len(lag))
+	}
+
+	if value := lag[0].GetGauge().GetValue(); value != want {
+		t.Errorf("got a total lag of %v, want %v", value, want)
+	}
+}
+
+// burrowSeries counts the series of burrow's metrics, leaving out those
+// about the exporter, and tells their levels of detail.
+func burrowSeries(families map[string]*dto.MetricFamily) (int, map[string]bool) {
+	series := 0
+	levels := make(map[string]bool)
+
+	for name, family := range families {
+		if strings.HasPrefix(name, "burrow_") || name == "kafka_burrow_endpoint_active" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			series++
+			if histogram := metric.GetHistogram(); histogram != nil {
+				series += len(histogram.GetBucket()) + 2
+			}
+
+			for _, label := range metric.GetLabel() {
+				levels[label.GetName()] = true
+			}
+		}
+	}
+
+	return series, levels
+}
+
+func TestCollectMaxSeries(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(1, 5, 4))
+	defer mock.Close()
+
+	collect := func(opts ...exporter.CollectorOption) (int, map[string]bool) {
+		client := mock.Client(3)
+		defer client.Close()
+
+		return burrowSeries(gather(t, exporter.NewCollector(client, "", opts...)))
+	}
+
+	total, _ := collect()
+
+	tests := []struct {
+		name      string
+		maxSeries int
+		partition bool
+		topic     bool
+	}{
+		{name: "within the limit", maxSeries: total, partition: true, topic: true},
+		{name: "partition metrics dropped", maxSeries: total - 1, topic: true},
+		{name: "truncated", maxSeries: 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			series, levels := collect(exporter.WithMaxSeries(test.maxSeries))
+
+			if series > test.maxSeries {
+				t.Errorf("got %d series, over the limit of %d", series, test.maxSeries)
+			}
+
+			if levels["partition"] != test.partition || levels["topic"] != test.topic {
+				t.Errorf("got partition metrics %v and topic metrics %v, want %v and %v", levels["partition"], levels["topic"], test.partition, test.topic)
+			}
+		})
+	}
+}
+
+func TestCollectIncrementalRefresh(t *testing.T) {
+	fixture := burrowtest.Synthetic(1, 2, 1)
+
+	mock := burrowtest.NewServer(fixture)
+	defer mock.Close()
+
+	burrow := newCountingBurrow(mock.Config.Handler)
+	defer burrow.Close()
+
+	client := exporter.NewBurrowClient([]string{burrow.URL}, 3)
+	defer client.Close()
+
+	c := exporter.NewCollector(client, "", exporter.WithIncrementalRefresh(3, 0))
+
+	// commit makes group-1 commit, copying the fixture so the mock never
+	// reads it while it's modified.
+	commit := func() {
+		data, err := json.Marshal(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fixture = &burrowtest.Fixture{}
+		if err := json.Unmarshal(data, fixture); err != nil {
+			t.Fatal(err)
+		}
+
+		fixture.Clusters["cluster-0"].Consumers["group-1"].Partitions[0].End.Timestamp++
+		mock.SetFixture(fixture)
+	}
+
+	tests := []struct {
+		name string
+		// idle and active are the fetches of group-0, which never
+		// commits, and of group-1 which commits before every scrape.
+		idle, active int
+	}{
+		{name: "full", idle: 1, active: 1},
+		// The groups only fetched once aren't known to be idle yet.
+		{name: "first incremental", idle: 2, active: 2},
+		{name: "second incremental", idle: 2, active: 3},
+		{name: "full again", idle: 3, active: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commit()
+			gather(t, c)
+
+			idle := burrow.count("/v3/kafka/cluster-0/consumer/group-0/lag")
+			active := burrow.count("/v3/kafka/cluster-0/consumer/group-1/lag")
+
+			if idle != test.idle || active != test.active {
+				t.Errorf("got %d fetches of the idle group and %d of the active one, want %d and %d", idle, active, test.idle, test.active)
+			}
+		})
+	}
+
+	// The reused status of the idle group is still exported.
+	if lag := gather(t, c)["kafka_burrow_total_lag"].GetMetric(); len(lag) != 2 {
+		t.Errorf("got the total lag of %d groups, want 2", len(lag))
+	}
+}
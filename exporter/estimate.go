@@ -0,0 +1,91 @@
+package exporter
+
+import "time"
+
+// WindowSeconds returns the time span of burrow's evaluation window, from
+// the first to the latest offset commit in it.
+func (p Partition) WindowSeconds() float64 {
+	return float64(p.End.Timestamp-p.Start.Timestamp) / 1000
+}
+
+// WindowOffsets returns the number of offset commits in burrow's
+// evaluation window, its empty slots are null.
+func (p ConsumerPartition) WindowOffsets() int {
+	n := 0
+	for _, offset := range p.Offsets {
+		if offset != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+// HeadRate returns the rate the partition's log end offset grew at over
+// burrow's evaluation window, in messages per second. It's false when
+// the window doesn't span any time.
+func (p Partition) HeadRate() (float64, bool) {
+	window := p.WindowSeconds()
+	if window <= 0 {
+		return 0, false
+	}
+
+	return float64(p.End.MaxOffset-p.Start.MaxOffset) / window, true
+}
+
+// EstimatedTimeLag approximates how far behind the head the consumer is,
+// by dividing the current lag by the head rate. It's false when it can't
+// be estimated, i.e. when nothing was produced over the window while
+// there's lag.
+func (p Partition) EstimatedTimeLag() (time.Duration, bool) {
+	if p.CurrentLag <= 0 {
+		return 0, true
+	}
+
+	rate, ok := p.HeadRate()
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(float64(p.CurrentLag) / rate * float64(time.Second)), true
+}
+
+// Stalled reports whether the consumer hasn't committed a higher offset
+// over burrow's evaluation window while there's lag to consume.
+func (p Partition) Stalled() bool {
+	return p.CurrentLag > 0 && p.End.Offset <= p.Start.Offset
+}
+
+// ConsumeRate returns the rate the consumer committed offsets at over
+// burrow's evaluation window, in messages per second. It's false when the
+// window doesn't span any time.
+func (p Partition) ConsumeRate() (float64, bool) {
+	window := p.WindowSeconds()
+	if window <= 0 {
+		return 0, false
+	}
+
+	return float64(p.End.Offset-p.Start.Offset) / window, true
+}
+
+// CatchUpTime estimates how long the consumer needs to reach the head of
+// the partition, keeping the consume and produce rates seen over the
+// window. It's false when the consumer isn't catching up, i.e. it's not
+// consuming faster than messages are produced.
+func (p Partition) CatchUpTime() (time.Duration, bool) {
+	if p.CurrentLag <= 0 {
+		return 0, true
+	}
+
+	consumed, ok := p.ConsumeRate()
+	if !ok {
+		return 0, false
+	}
+
+	produced, _ := p.HeadRate()
+	if consumed <= produced {
+		return 0, false
+	}
+
+	return time.Duration(float64(p.CurrentLag) / (consumed - produced) * float64(time.Second)), true
+}
@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AnchoredRegexp compiles the regular expression anchored to match whole
+// names.
+func AnchoredRegexp(expr string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// NameFilter selects names, e.g. of clusters, groups or topics, by regex.
+type NameFilter struct {
+	// Include only selects the names matching it, when set.
+	Include *regexp.Regexp
+	// Exclude skips the names matching it, when set.
+	Exclude *regexp.Regexp
+}
+
+// Match reports whether the name is selected.
+func (f NameFilter) Match(name string) bool {
+	if f.Include != nil && !f.Include.MatchString(name) {
+		return false
+	}
+
+	if f.Exclude != nil && f.Exclude.MatchString(name) {
+		return false
+	}
+
+	return true
+}
+
+// TopicFilter selects the topics whose metrics are exported.
+type TopicFilter struct {
+	NameFilter
+
+	// ExcludeInternal skips kafka's internal topics, the ones starting with
+	// a double underscore, e.g. __consumer_offsets or __transaction_state.
+	ExcludeInternal bool
+}
+
+// Match reports whether the topic's metrics are exported.
+func (f TopicFilter) Match(topic string) bool {
+	if f.ExcludeInternal && strings.HasPrefix(topic, "__") {
+		return false
+	}
+
+	return f.NameFilter.Match(topic)
+}
+
+// filterPartitions returns the partitions of the topics matching, reusing
+// the partitions' array.
+func filterPartitions(partitions []Partition, match func(topic string) bool) []Partition {
+	filtered := partitions[:0]
+
+	for _, partition := range partitions {
+		if match(partition.Topic) {
+			filtered = append(filtered, partition)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"time"
+)
+
+// ScrapeRunningFor returns how long the ongoing scrape of burrow has been
+// running, 0 when there's none.
+func (c *Collector) ScrapeRunningFor() time.Duration {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	if c.scrapeStart.IsZero() {
+		return 0
+	}
+
+	return time.Since(c.scrapeStart)
+}
+
+func (c *Collector) setScrapeStart(start time.Time) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	c.scrapeStart = start
+}
+
+// Ready tells whether a scrape of burrow succeeded yet, and with
+// requireHealthy whether the last one did. A standby replica isn't ready.
+func (c *Collector) Ready(requireHealthy bool) bool {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	if c.standby {
+		return false
+	}
+
+	if requireHealthy {
+		return c.lastHealthy
+	}
+
+	return c.everHealthy
+}
+
+func (c *Collector) setScrapeResult(healthy bool) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	c.lastHealthy = healthy
+	c.everHealthy = c.everHealthy || healthy
+}
+
+// Standby tells whether another replica holds the leader lease, so this one
+// doesn't scrape burrow.
+func (c *Collector) Standby() bool {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	return c.standby
+}
+
+func (c *Collector) setStandby(standby bool) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	c.standby = standby
+}
@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// nativeHistogramMaxBuckets bounds the buckets of a native lag histogram,
+// its resolution is lowered rather than exceeding it.
+const nativeHistogramMaxBuckets = 160
+
+// nativeLagHistogram returns the histogram of the partitions' lag, as a
+// native histogram. Unlike the constant histograms, it's built by observing
+// each partition's lag, its buckets being only known by then. The worst
+// partition is observed with the exemplar, if any.
+func (c *Collector) nativeLagHistogram(partitions []Partition, worst Partition, exemplar prometheus.Labels, labels ...string) prometheus.Metric {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:                           "kafka_burrow_partition_lag_distribution",
+		Help:                           "The distribution of the current lag of the consumer group's partitions.",
+		Buckets:                        c.lagBuckets,
+		NativeHistogramBucketFactor:    c.lagNativeFactor,
+		NativeHistogramMaxBucketNumber: nativeHistogramMaxBuckets,
+	}, []string{"cluster", "group"}).WithLabelValues(labels...)
+
+	for _, partition := range partitions {
+		if exemplar != nil && partition.Topic == worst.Topic && partition.Partition == worst.Partition {
+			histogram.(prometheus.ExemplarObserver).ObserveWithExemplar(float64(partition.CurrentLag), exemplar)
+			exemplar = nil
+			continue
+		}
+
+		histogram.Observe(float64(partition.CurrentLag))
+	}
+
+	return histogram.(prometheus.Histogram)
+}
+
+// lagExemplar returns the exemplar labels pointing at the worst partition's
+// metrics, nil when unknown or too long for an exemplar.
+func (c *Collector) lagExemplar(worst Partition) prometheus.Labels {
+	if worst.Topic == "" || !c.matchTopic(worst.Topic) {
+		return nil
+	}
+
+	topic, _ := c.topicLabels(worst.Topic)
+	labels := prometheus.Labels{"topic": topic, "partition": strconv.Itoa(int(worst.Partition))}
+
+	runes := 0
+	for name, value := range labels {
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+
+	if runes > prometheus.ExemplarMaxRunes {
+		return nil
+	}
+
+	return labels
+}
@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// KerberosConfig holds the settings for SPNEGO (Negotiate) authentication.
+// When Keytab is set, Principal (user@REALM) is logged in with it,
+// otherwise the tickets in the credential cache are used.
+type KerberosConfig struct {
+	ConfigFile string
+	Keytab     string
+	Principal  string
+	CCache     string
+	// SPN defaults to HTTP/<burrow host> when empty.
+	SPN string
+}
+
+// defaultCCache mimics the MIT kerberos lookup of the credential cache.
+func defaultCCache() string {
+	if name := os.Getenv("KRB5CCNAME"); name != "" {
+		return strings.TrimPrefix(name, "FILE:")
+	}
+
+	return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+}
+
+func newKerberosClient(kc KerberosConfig) (*client.Client, error) {
+	cfg, err := config.Load(kc.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading kerberos config (%v): %v", kc.ConfigFile, err)
+	}
+
+	if kc.Keytab != "" {
+		kt, err := keytab.Load(kc.Keytab)
+		if err != nil {
+			return nil, fmt.Errorf("loading keytab (%v): %v", kc.Keytab, err)
+		}
+
+		parts := strings.SplitN(kc.Principal, "@", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid kerberos principal %q, expected user@REALM", kc.Principal)
+		}
+
+		return client.NewWithKeytab(parts[0], parts[1], kt, cfg, client.DisablePAFXFAST(true)), nil
+	}
+
+	ccachePath := kc.CCache
+	if ccachePath == "" {
+		ccachePath = defaultCCache()
+	}
+
+	ccache, err := credentials.LoadCCache(ccachePath)
+	if err != nil {
+		return nil, fmt.Errorf("loading credential cache (%v): %v", ccachePath, err)
+	}
+
+	return client.NewFromCCache(ccache, cfg, client.DisablePAFXFAST(true))
+}
+
+// negotiateTransport adds a SPNEGO token to each request.
+type negotiateTransport struct {
+	client *client.Client
+	spn    string
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *negotiateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	if err := spnego.SetSPNEGOHeader(t.client, req, t.spn); err != nil {
+		return nil, fmt.Errorf("spnego: %v", err)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// WithKerberos authenticates every request to burrow using SPNEGO, it
+// fails when the kerberos configuration or credentials can't be loaded.
+func WithKerberos(kc KerberosConfig) (ClientOption, error) {
+	cl, err := newKerberosClient(kc)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(bc *BurrowClient) {
+		bc.client.Transport = &negotiateTransport{
+			client: cl,
+			spn:    kc.SPN,
+			base:   bc.roundTripper(),
+		}
+	}, nil
+}
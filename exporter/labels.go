@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// ClusterLabels maps burrow cluster names to extra labels, e.g. a friendly
+// alias or the environment, added to all the metrics of the cluster.
+type ClusterLabels map[string]prometheus.Labels
+
+// ParseClusterLabels parses label mappings in the <cluster>:<label>=<value>
+// form.
+func ParseClusterLabels(mappings []string) (ClusterLabels, error) {
+	labels := make(ClusterLabels)
+
+	for _, mapping := range mappings {
+		i := strings.Index(mapping, ":")
+		j := strings.Index(mapping, "=")
+		if i <= 0 || j < i {
+			return nil, fmt.Errorf("invalid cluster label %q, expected <cluster>:<label>=<value>", mapping)
+		}
+
+		cluster, name, value := mapping[:i], mapping[i+1:j], mapping[j+1:]
+		if !model.LabelName(name).IsValid() {
+			return nil, fmt.Errorf("invalid label name %q in cluster label %q", name, mapping)
+		}
+
+		if labels[cluster] == nil {
+			labels[cluster] = make(prometheus.Labels)
+		}
+		labels[cluster][name] = value
+	}
+
+	return labels, nil
+}
+
+// forCluster returns the labels of the cluster, every label given to any
+// cluster is set, empty when not given to this one, so all the clusters'
+// metrics have the same label names.
+func (l ClusterLabels) forCluster(cluster string) prometheus.Labels {
+	labels := make(prometheus.Labels)
+
+	for _, clusterLabels := range l {
+		for name := range clusterLabels {
+			labels[name] = ""
+		}
+	}
+
+	for name, value := range l[cluster] {
+		labels[name] = value
+	}
+
+	return labels
+}
+
+// metricList is a collector of already collected metrics.
+type metricList []prometheus.Metric
+
+func (l metricList) Describe(ch chan<- *prometheus.Desc) {}
+
+func (l metricList) Collect(ch chan<- prometheus.Metric) {
+	for _, metric := range l {
+		ch <- metric
+	}
+}
+
+// collectAll collects all the metrics of c.
+func collectAll(c prometheus.Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var metrics []prometheus.Metric
+	for metric := range ch {
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// collectorCapture is a Registerer keeping the last collector registered.
+type collectorCapture struct {
+	collector prometheus.Collector
+}
+
+func (r *collectorCapture) Register(c prometheus.Collector) error {
+	r.collector = c
+	return nil
+}
+
+func (r *collectorCapture) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		r.Register(c)
+	}
+}
+
+func (r *collectorCapture) Unregister(c prometheus.Collector) bool {
+	return false
+}
+
+// withLabels returns a collector adding the labels to all the metrics of
+// c, reusing the wrapping done by prometheus.WrapRegistererWith.
+func withLabels(labels prometheus.Labels, c prometheus.Collector) prometheus.Collector {
+	capture := &collectorCapture{}
+	prometheus.WrapRegistererWith(labels, capture).MustRegister(c)
+
+	return capture.collector
+}
@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"sort"
+	"time"
+)
+
+// ClusterLag is the lag of the exported consumer groups of a cluster, as of
+// its last scrape.
+type ClusterLag struct {
+	Cluster   string                `json:"cluster"`
+	ScrapedAt time.Time             `json:"scraped_at"`
+	Groups    []ConsumerGroupStatus `json:"groups"`
+}
+
+// Lag returns the lag of the consumer groups in the latest snapshots, by
+// cluster name. It's shared with the other callers, so it mustn't be
+// modified.
+func (c *Collector) Lag() []ClusterLag {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+
+	return c.lag
+}
+
+// publishLag makes the lag of the current snapshots available to Lag, which
+// is called without the collector's mutex, and with notify sends the lag of
+// the refreshed clusters to the subscribers.
+func (c *Collector) publishLag(notify bool) {
+	c.statusMutex.Lock()
+	previous := make(map[string]time.Time)
+	for _, cluster := range c.lag {
+		previous[cluster.Cluster] = cluster.ScrapedAt
+	}
+	c.statusMutex.Unlock()
+
+	lag := make([]ClusterLag, 0, len(c.snapshots))
+	var updates []GroupUpdate
+
+	for cluster, snapshot := range c.snapshots {
+		lag = append(lag, ClusterLag{Cluster: cluster, ScrapedAt: snapshot.at, Groups: snapshot.groups})
+
+		if !snapshot.at.After(previous[cluster]) {
+			continue
+		}
+
+		for _, group := range snapshot.groups {
+			updates = append(updates, GroupUpdate{
+				Cluster:   cluster,
+				Group:     group.Group,
+				Status:    group.Status,
+				TotalLag:  group.TotalLag,
+				ScrapedAt: snapshot.at,
+			})
+		}
+	}
+
+	sort.Slice(lag, func(i, j int) bool { return lag[i].Cluster < lag[j].Cluster })
+
+	c.statusMutex.Lock()
+	c.lag = lag
+	c.statusMutex.Unlock()
+
+	if notify {
+		c.subscriptions.send(updates)
+	}
+}
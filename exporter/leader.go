@@ -0,0 +1,148 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// LeaseLock elects the leader among exporter replicas sharing a lease file,
+// only the leader scrapes burrow. The lease is renewed by the leader on each
+// collection, and taken over by another replica once it wasn't renewed for
+// the lease duration, which must thus exceed the scrape interval.
+type LeaseLock struct {
+	Path     string
+	Identity string
+	Duration time.Duration
+
+	leader bool
+}
+
+// lease is the content of the lease file.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Renewed time.Time `json:"renewed"`
+}
+
+// The attempts at taking the lock of the lease held by another replica,
+// which only holds it while reading and writing the lease.
+const (
+	leaseLockAttempts = 10
+	leaseLockRetry    = 50 * time.Millisecond
+)
+
+// acquire renews the lease when held or expired, and tells whether this
+// replica is the leader. The lease is read and written under its lock, so
+// two replicas can't both take it over.
+func (l *LeaseLock) acquire(now time.Time) bool {
+	unlock, err := l.lock(now)
+	if err != nil {
+		log.With("err", err).Errorf("Failed locking the leader lease (%v)", l.Path)
+		return l.setLeader(false)
+	}
+	defer unlock()
+
+	current, err := l.read()
+	if err != nil && !os.IsNotExist(err) {
+		log.With("err", err).Errorf("Failed reading the leader lease (%v)", l.Path)
+		return l.setLeader(false)
+	}
+
+	if current.Holder != l.Identity && now.Sub(current.Renewed) < l.Duration {
+		return l.setLeader(false)
+	}
+
+	if err := l.write(lease{Holder: l.Identity, Renewed: now}); err != nil {
+		log.With("err", err).Errorf("Failed renewing the leader lease (%v)", l.Path)
+		return l.setLeader(false)
+	}
+
+	return l.setLeader(true)
+}
+
+// lock creates the lock file of the lease exclusively, retrying while
+// another replica holds it. A lock older than the lease duration was left
+// by a replica that died holding it, and is broken. The lock relies on the
+// exclusive creation of files, which some network filesystems lack.
+func (l *LeaseLock) lock(now time.Time) (func(), error) {
+	path := l.Path + ".lock"
+
+	for attempt := 1; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if attempt >= leaseLockAttempts {
+			return nil, errors.New("the lock is held by another replica")
+		}
+
+		if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > l.Duration {
+			log.Warnf("Breaking the stale lock of the leader lease (%v)", path)
+			os.Remove(path)
+			continue
+		}
+
+		time.Sleep(leaseLockRetry)
+	}
+}
+
+func (l *LeaseLock) setLeader(leader bool) bool {
+	if leader != l.leader {
+		if leader {
+			log.Infof("Acquired the leader lease (%v) as %v", l.Path, l.Identity)
+		} else {
+			log.Infof("Not holding the leader lease (%v), standing by", l.Path)
+		}
+	}
+
+	l.leader = leader
+	return leader
+}
+
+func (l *LeaseLock) read() (lease, error) {
+	var current lease
+
+	data, err := ioutil.ReadFile(l.Path)
+	if err != nil {
+		return current, err
+	}
+
+	return current, json.Unmarshal(data, &current)
+}
+
+// write replaces the lease file atomically, so it's never read partly
+// written.
+func (l *LeaseLock) write(renewed lease) error {
+	data, err := json.Marshal(renewed)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(l.Path), filepath.Base(l.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.Path)
+}
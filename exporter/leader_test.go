@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeaseLockAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lease")
+	now := time.Now()
+
+	a := &LeaseLock{Path: path, Identity: "a", Duration: time.Minute}
+	b := &LeaseLock{Path: path, Identity: "b", Duration: time.Minute}
+
+	tests := []struct {
+		name   string
+		lock   *LeaseLock
+		at     time.Time
+		leader bool
+	}{
+		{name: "first takes it", lock: a, at: now, leader: true},
+		{name: "other stands by", lock: b, at: now.Add(time.Second), leader: false},
+		{name: "holder renews", lock: a, at: now.Add(30 * time.Second), leader: true},
+		{name: "other stands by while renewed", lock: b, at: now.Add(80 * time.Second), leader: false},
+		{name: "other takes it over once expired", lock: b, at: now.Add(91 * time.Second), leader: true},
+		{name: "previous holder stands by", lock: a, at: now.Add(92 * time.Second), leader: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if leader := test.lock.acquire(test.at); leader != test.leader {
+				t.Errorf("got leader %v, want %v", leader, test.leader)
+			}
+		})
+	}
+}
+
+func TestLeaseLockConcurrentAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lease")
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	leaders := make([]bool, 8)
+
+	for i := range leaders {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			lock := &LeaseLock{Path: path, Identity: string(rune('a' + i)), Duration: time.Minute}
+			leaders[i] = lock.acquire(now)
+		}(i)
+	}
+
+	wg.Wait()
+
+	count := 0
+	for _, leader := range leaders {
+		if leader {
+			count++
+		}
+	}
+
+	if count != 1 {
+		t.Errorf("got %d leaders, want 1", count)
+	}
+}
+
+func TestLeaseLockStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lease")
+	lock := &LeaseLock{Path: path, Identity: "a", Duration: time.Minute}
+
+	// A replica died holding the lock.
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if lock.acquire(time.Now()) {
+		t.Fatal("took the lease while another replica held its lock")
+	}
+
+	if !lock.acquire(time.Now().Add(2 * time.Minute)) {
+		t.Error("didn't break the stale lock")
+	}
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("the lock is left behind: %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"runtime"
+
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+const (
+	// The collector degrades above this ratio of the memory budget, and
+	// recovers below the lower one, so it doesn't flap around the limit.
+	degradeMemoryRatio = 0.9
+	recoverMemoryRatio = 0.7
+)
+
+// checkMemory degrades the collector to the consumer group level metrics
+// when the memory used approaches the budget, and recovers once it went
+// down enough.
+func (c *Collector) checkMemory() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	used := float64(stats.Sys - stats.HeapReleased)
+
+	switch {
+	case !c.degraded && used > degradeMemoryRatio*float64(c.memoryBudget):
+		log.Warnf("Using %v of the %v bytes memory budget, dropping the partition metrics", uint64(used), c.memoryBudget)
+		c.degraded = true
+	case c.degraded && used < recoverMemoryRatio*float64(c.memoryBudget):
+		log.Infof("Using %v of the %v bytes memory budget, exporting the partition metrics again", uint64(used), c.memoryBudget)
+		c.degraded = false
+	}
+}
+
+// WithMemoryBudget drops the partition metrics, keeping the consumer group
+// level ones, while the memory used approaches the budget in bytes.
+func WithMemoryBudget(budget uint64) CollectorOption {
+	return func(c *Collector) {
+		c.memoryBudget = budget
+	}
+}
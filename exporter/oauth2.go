@@ -0,0 +1,146 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are refreshed a bit before they actually expire, so in-flight
+// requests don't race the expiry.
+const oauth2ExpiryDelta = 10 * time.Second
+
+// OAuth2Config holds the client-credentials grant settings, used when
+// burrow is fronted by an OAuth2-aware proxy.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type oauth2TokenResp struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// oauth2Transport adds a bearer token to each request, fetching a new one
+// via the client-credentials grant whenever the cached one expires.
+type oauth2Transport struct {
+	config OAuth2Config
+	base   http.RoundTripper
+
+	mutex  sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (t *oauth2Transport) fetchToken(ctx context.Context) (*oauth2TokenResp, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(t.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(t.config.ClientID), url.QueryEscape(t.config.ClientSecret))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	token := &oauth2TokenResp{}
+	if err := json.NewDecoder(resp.Body).Decode(token); err != nil {
+		return nil, fmt.Errorf("decoding token response (status %v): %v", resp.StatusCode, err)
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("token request failed: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("token request failed with status %v", resp.StatusCode)
+	}
+
+	return token, nil
+}
+
+func (t *oauth2Transport) getToken(ctx context.Context) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || time.Now().Before(t.expiry)) {
+		return t.token, nil
+	}
+
+	token, err := t.fetchToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	t.token = token.AccessToken
+	t.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - oauth2ExpiryDelta)
+	}
+
+	return t.token, nil
+}
+
+func (t *oauth2Transport) invalidate(token string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.token == token {
+		t.token = ""
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.getToken(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: %v", err)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token may have been revoked before its expiry, make sure the
+	// next request fetches a fresh one.
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.invalidate(token)
+	}
+
+	return resp, nil
+}
+
+// WithOAuth2 authenticates every request to burrow with a token obtained
+// through the OAuth2 client-credentials grant.
+func WithOAuth2(config OAuth2Config) ClientOption {
+	return func(bc *BurrowClient) {
+		bc.client.Transport = &oauth2Transport{
+			config: config,
+			base:   bc.roundTripper(),
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// snapshotExt is the extension of the cluster snapshot files, which are in
+// the text exposition format, while the lag of their consumer groups is
+// kept next to them in JSON.
+const (
+	snapshotExt = ".prom"
+	groupsExt   = ".json"
+)
+
+// snapshotPath returns the file of the cluster's snapshot in dir.
+func snapshotPath(dir, cluster string) string {
+	return filepath.Join(dir, cluster+snapshotExt)
+}
+
+// saveSnapshot writes the snapshot of the cluster, the time of its scrape
+// is kept as the files' modification time.
+func saveSnapshot(dir, cluster string, snapshot *clusterSnapshot) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(metricList(snapshot.metrics)); err != nil {
+		return err
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(&buf, family); err != nil {
+			return err
+		}
+	}
+
+	groups, err := json.Marshal(snapshot.groups)
+	if err != nil {
+		return err
+	}
+
+	// The groups go first, so the metrics are never loaded with the groups
+	// of an older scrape.
+	if err := writeFile(dir, cluster+groupsExt, groups, snapshot.at); err != nil {
+		return err
+	}
+
+	return writeFile(dir, cluster+snapshotExt, buf.Bytes(), snapshot.at)
+}
+
+// writeFile replaces the file in dir atomically, so it's never loaded
+// partly written, setting its modification time.
+func writeFile(dir, name string, data []byte, modTime time.Time) error {
+	tmp, err := ioutil.TempFile(dir, name+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// loadSnapshots reads the cluster snapshots saved in dir.
+func loadSnapshots(dir string) (map[string]*clusterSnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+snapshotExt))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make(map[string]*clusterSnapshot)
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(f)
+		f.Close()
+		if err != nil {
+			log.With("err", err).Warnf("Failed parsing the snapshot (%v), skipping", file)
+			continue
+		}
+
+		snapshot := &clusterSnapshot{at: info.ModTime()}
+		for _, family := range families {
+			snapshot.metrics = append(snapshot.metrics, familyMetrics(family)...)
+		}
+
+		// The snapshots saved before the groups were kept have none.
+		groupsFile := strings.TrimSuffix(file, snapshotExt) + groupsExt
+		if data, err := ioutil.ReadFile(groupsFile); err == nil {
+			if err := json.Unmarshal(data, &snapshot.groups); err != nil {
+				log.With("err", err).Warnf("Failed parsing the consumer groups of the snapshot (%v), skipping them", groupsFile)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		snapshots[strings.TrimSuffix(filepath.Base(file), snapshotExt)] = snapshot
+	}
+
+	return snapshots, nil
+}
+
+// familyMetrics recreates the metrics of a parsed metric family.
+func familyMetrics(family *dto.MetricFamily) []prometheus.Metric {
+	var metrics []prometheus.Metric
+
+	for _, m := range family.Metric {
+		var names, values []string
+		for _, label := range m.Label {
+			names = append(names, label.GetName())
+			values = append(values, label.GetValue())
+		}
+
+		desc := prometheus.NewDesc(family.GetName(), family.GetHelp(), names, nil)
+
+		var metric prometheus.Metric
+		var err error
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.Counter.GetValue(), values...)
+		case dto.MetricType_GAUGE:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.Gauge.GetValue(), values...)
+		case dto.MetricType_UNTYPED:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.Untyped.GetValue(), values...)
+		case dto.MetricType_HISTOGRAM:
+			buckets := make(map[float64]uint64)
+			for _, bucket := range m.Histogram.Bucket {
+				// The +Inf bucket is the sample count.
+				if !math.IsInf(bucket.GetUpperBound(), +1) {
+					buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+				}
+			}
+
+			metric, err = prometheus.NewConstHistogram(desc, m.Histogram.GetSampleCount(), m.Histogram.GetSampleSum(), buckets, values...)
+		default:
+			continue
+		}
+
+		if err != nil {
+			log.With("err", err).Warnf("Failed restoring a %v metric from its snapshot, skipping", family.GetName())
+			continue
+		}
+
+		if m.TimestampMs != nil {
+			metric = prometheus.NewMetricWithTimestamp(time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond)), metric)
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+// WithSnapshotDir saves the metrics and consumer group lag of each cluster's
+// scrapes under dir, and restores them on startup. The first collection
+// serves them right away and refreshes them in the background, after which
+// they're served like those of the previous scrape, i.e. until the
+// cluster's refresh interval elapsed, or within the stale grace period when
+// scraping fails.
+func WithSnapshotDir(dir string) (CollectorOption, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := loadSnapshots(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Collector) {
+		c.snapshotDir = dir
+		c.snapshots = snapshots
+		c.restored = len(snapshots) > 0
+	}, nil
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requestQueue bounds the number of concurrent requests to burrow, the
+// others wait for a slot, so a slow burrow isn't piled up with requests.
+type requestQueue struct {
+	// slots is nil when the requests aren't bounded.
+	slots chan struct{}
+
+	inFlight prometheus.Gauge
+	queued   prometheus.Gauge
+	dropped  prometheus.Counter
+}
+
+func newRequestQueue() *requestQueue {
+	return &requestQueue{
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "burrow_exporter_requests_in_flight",
+			Help: "Number of requests to burrow currently in flight.",
+		}),
+		queued: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "burrow_exporter_requests_queued",
+			Help: "Number of requests to burrow currently waiting for a slot.",
+		}),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "burrow_exporter_requests_dropped_total",
+			Help: "Total number of requests to burrow given up while waiting for a slot.",
+		}),
+	}
+}
+
+// acquire waits for a slot until ctx is done.
+func (q *requestQueue) acquire(ctx context.Context) error {
+	if q.slots != nil {
+		q.queued.Inc()
+		defer q.queued.Dec()
+
+		select {
+		case q.slots <- struct{}{}:
+		case <-ctx.Done():
+			q.dropped.Inc()
+			return ctx.Err()
+		}
+	}
+
+	q.inFlight.Inc()
+	return nil
+}
+
+func (q *requestQueue) release() {
+	q.inFlight.Dec()
+
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+func (q *requestQueue) Describe(ch chan<- *prometheus.Desc) {
+	q.inFlight.Describe(ch)
+	q.queued.Describe(ch)
+	q.dropped.Describe(ch)
+}
+
+func (q *requestQueue) Collect(ch chan<- prometheus.Metric) {
+	q.inFlight.Collect(ch)
+	q.queued.Collect(ch)
+	q.dropped.Collect(ch)
+}
+
+// WithMaxConcurrentRequests bounds the number of concurrent requests to
+// burrow, the others wait for a slot within their timeout.
+func WithMaxConcurrentRequests(max int) ClientOption {
+	return func(bc *BurrowClient) {
+		bc.queue.slots = make(chan struct{}, max)
+	}
+}
@@ -0,0 +1,46 @@
+package exporter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+func TestClientRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	mock := burrowtest.NewServer(burrowtest.Synthetic(1, 1, 2))
+
+	recording := mock.Client(3, exporter.WithRecording(dir))
+	recorded, err := recording.ConsumerGroupLag("cluster-0", "group-0")
+	recording.Close()
+	mock.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "v3", "kafka", "cluster-0", "consumer", "group-0", "lag.json")); err != nil {
+		t.Fatalf("the response wasn't recorded: %v", err)
+	}
+
+	// The replay doesn't reach the mock, which is gone.
+	replaying := exporter.NewBurrowClient([]string{mock.URL}, 3, exporter.WithReplay(dir))
+	defer replaying.Close()
+
+	replayed, err := replaying.ConsumerGroupLag("cluster-0", "group-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replayed.Status.TotalLag != recorded.Status.TotalLag || len(replayed.Status.Partitions) != len(recorded.Status.Partitions) {
+		t.Errorf("replayed %+v, want %+v", replayed.Status, recorded.Status)
+	}
+
+	if _, err := replaying.ListClusters(); err == nil {
+		t.Error("replayed the cluster list, which wasn't recorded")
+	}
+}
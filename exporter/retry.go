@@ -0,0 +1,126 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+)
+
+// A quiet exporter sends few requests per interval, this many retries are
+// always allowed so the budget doesn't prevent retrying altogether.
+const retryBudgetMinRetries = 10
+
+// defaultRetryBudgetInterval applies to the budgets without an interval.
+const defaultRetryBudgetInterval = time.Minute
+
+// RetryPolicy configures how failed burrow requests are retried, after
+// all the base URLs were tried in turn.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries of a failed request, 0 disables retrying.
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled on every following one.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between the retries, 0 doesn't cap it.
+	MaxBackoff time.Duration
+	// BudgetRatio is the maximum ratio of retries to requests within
+	// BudgetInterval, 0 disables the budget. The interval defaults to a
+	// minute.
+	BudgetRatio    float64
+	BudgetInterval time.Duration
+}
+
+// retryBudget limits the retries to a ratio of the requests sent within
+// the current interval, so retries can't amplify a burrow outage.
+type retryBudget struct {
+	ratio    float64
+	interval time.Duration
+
+	mutex    sync.Mutex
+	start    time.Time
+	requests int
+	retries  int
+}
+
+func (b *retryBudget) roll(now time.Time) {
+	if now.Sub(b.start) >= b.interval {
+		b.start = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
+
+func (b *retryBudget) request() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.roll(time.Now())
+	b.requests++
+}
+
+func (b *retryBudget) allowRetry() bool {
+	if b.ratio <= 0 {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.roll(time.Now())
+
+	if b.retries >= retryBudgetMinRetries && float64(b.retries+1) > b.ratio*float64(b.requests) {
+		return false
+	}
+
+	b.retries++
+	return true
+}
+
+// nextBackoff doubles the delay before the next retry, up to the maximum.
+func (p RetryPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+
+	return backoff
+}
+
+// sleep waits before sending the next requests, e.g. a retry, returning
+// early once the client is closed, or right away when the deadline would
+// pass meanwhile as there wouldn't be time left for them.
+func (bc *BurrowClient) sleep(backoff time.Duration) error {
+	bc.mutex.Lock()
+	deadline := bc.deadline
+	bc.mutex.Unlock()
+
+	if !deadline.IsZero() && deadline.Before(time.Now().Add(backoff)) {
+		return ErrDeadlineExceeded
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-bc.ctx.Done():
+		return ErrClosed
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WithRetryPolicy retries failed requests to burrow.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(bc *BurrowClient) {
+		// Without an interval the budget would be reset on every request,
+		// never limiting the retries.
+		interval := policy.BudgetInterval
+		if interval <= 0 {
+			interval = defaultRetryBudgetInterval
+		}
+
+		bc.retryPolicy = policy
+		bc.retryBudget = &retryBudget{
+			ratio:    policy.BudgetRatio,
+			interval: interval,
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		interval time.Duration
+		requests int
+		// allowed is the number of retries allowed out of 100.
+		allowed int
+	}{
+		{name: "no budget", ratio: 0, interval: time.Minute, requests: 10, allowed: 100},
+		{name: "minimum retries", ratio: 0.1, interval: time.Minute, requests: 10, allowed: retryBudgetMinRetries},
+		{name: "ratio of the requests", ratio: 0.1, interval: time.Minute, requests: 500, allowed: 50},
+		// The budget isn't reset on every request without an interval.
+		{name: "default interval", ratio: 0.1, interval: 0, requests: 10, allowed: retryBudgetMinRetries},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bc := &BurrowClient{}
+			WithRetryPolicy(RetryPolicy{BudgetRatio: test.ratio, BudgetInterval: test.interval})(bc)
+
+			for i := 0; i < test.requests; i++ {
+				bc.retryBudget.request()
+			}
+
+			allowed := 0
+			for i := 0; i < 100; i++ {
+				if bc.retryBudget.allowRetry() {
+					allowed++
+				}
+			}
+
+			if allowed != test.allowed {
+				t.Errorf("allowed %d retries, want %d", allowed, test.allowed)
+			}
+		})
+	}
+}
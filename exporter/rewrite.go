@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RewriteRule rewrites the names matching its regular expression, e.g. to
+// strip the instance ID out of a consumer group name.
+type RewriteRule struct {
+	Regexp *regexp.Regexp
+	// Replacement is expanded as in regexp.Regexp.ReplaceAllString, so it
+	// may refer to the capture groups, e.g. $1 or ${name}.
+	Replacement string
+}
+
+// ParseRewriteRule parses a rule in the <regex>=<replacement> form, the
+// regex is anchored to match the whole name.
+func ParseRewriteRule(rule string) (RewriteRule, error) {
+	i := strings.LastIndex(rule, "=")
+	if i <= 0 {
+		return RewriteRule{}, fmt.Errorf("invalid rewrite rule %q, expected <regex>=<replacement>", rule)
+	}
+
+	re, err := AnchoredRegexp(rule[:i])
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("invalid rewrite rule %q: %v", rule, err)
+	}
+
+	return RewriteRule{Regexp: re, Replacement: rule[i+1:]}, nil
+}
+
+// RewriteRules are applied in order, the first rule matching a name
+// rewrites it.
+type RewriteRules []RewriteRule
+
+// ParseRewriteRules parses each rule with ParseRewriteRule.
+func ParseRewriteRules(rules []string) (RewriteRules, error) {
+	var parsed RewriteRules
+
+	for _, rule := range rules {
+		r, err := ParseRewriteRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, r)
+	}
+
+	return parsed, nil
+}
+
+// Apply returns the name rewritten by the first matching rule, it's false
+// when none matches.
+func (rs RewriteRules) Apply(name string) (string, bool) {
+	for _, r := range rs {
+		if r.Regexp.MatchString(name) {
+			return r.Regexp.ReplaceAllString(name, r.Replacement), true
+		}
+	}
+
+	return "", false
+}
+
+// Rewrite returns the name rewritten by the first matching rule, or as is
+// when none matches.
+func (rs RewriteRules) Rewrite(name string) string {
+	if rewritten, ok := rs.Apply(name); ok {
+		return rewritten
+	}
+
+	return name
+}
+
+// mergeGroupStatus returns the evaluation of two groups rewritten to the
+// same name, summing up their lag and keeping the worst of their status.
+func mergeGroupStatus(a, b *ConsumerGroupStatusResp) *ConsumerGroupStatusResp {
+	merged := *a
+	status := &merged.Status
+
+	status.Partitions = append(append([]Partition(nil), a.Status.Partitions...), b.Status.Partitions...)
+	status.PartitionCount += b.Status.PartitionCount
+	status.TotalLag += b.Status.TotalLag
+
+	if b.Status.MaxLag.CurrentLag > status.MaxLag.CurrentLag {
+		status.MaxLag = b.Status.MaxLag
+	}
+
+	if Status[b.Status.Status] > Status[status.Status] {
+		status.Status = b.Status.Status
+	}
+
+	if b.Status.Complete < status.Complete {
+		status.Complete = b.Status.Complete
+	}
+
+	return &merged
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import "testing"
+
+func TestRewriteRules(t *testing.T) {
+	rules, err := ParseRewriteRules([]string{
+		`(.+)-[0-9a-f]{8}=$1`,
+		`legacy\.(?P<name>.+)=${name}-legacy`,
+		`a=b=c`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		rewritten string
+	}{
+		{name: "payments-1a2b3c4d", rewritten: "payments"},
+		{name: "legacy.orders", rewritten: "orders-legacy"},
+		// The rules are anchored, so partial matches don't rewrite.
+		{name: "payments-1a2b3c4d-retry", rewritten: "payments-1a2b3c4d-retry"},
+		{name: "not.legacy.orders", rewritten: "not.legacy.orders"},
+		// The last equal sign separates the replacement.
+		{name: "a=b", rewritten: "c"},
+		{name: "orders", rewritten: "orders"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if rewritten := rules.Rewrite(test.name); rewritten != test.rewritten {
+				t.Errorf("got %q, want %q", rewritten, test.rewritten)
+			}
+
+			// Apply only tells the names matching a rule.
+			if _, matched := rules.Apply(test.name); matched != (test.rewritten != test.name) {
+				t.Errorf("got matched %v, want %v", matched, test.rewritten != test.name)
+			}
+		})
+	}
+}
+
+func TestParseRewriteRuleErrors(t *testing.T) {
+	for _, rule := range []string{"", "orders", "=orders", "(orders=$1"} {
+		if _, err := ParseRewriteRule(rule); err == nil {
+			t.Errorf("parsed the invalid rule %q", rule)
+		}
+	}
+}
+
+func TestMergeGroupStatus(t *testing.T) {
+	a := &ConsumerGroupStatusResp{Status: ConsumerGroupStatus{
+		Group:          "payments-1a2b3c4d",
+		Status:         "OK",
+		Complete:       1,
+		MaxLag:         Partition{Topic: "orders", Partition: 0, CurrentLag: 10},
+		Partitions:     []Partition{{Topic: "orders", Partition: 0, CurrentLag: 10}},
+		PartitionCount: 1,
+		TotalLag:       10,
+	}}
+	b := &ConsumerGroupStatusResp{Status: ConsumerGroupStatus{
+		Group:          "payments-5e6f7a8b",
+		Status:         "WARN",
+		Complete:       0.5,
+		MaxLag:         Partition{Topic: "orders", Partition: 1, CurrentLag: 30},
+		Partitions:     []Partition{{Topic: "orders", Partition: 1, CurrentLag: 30}},
+		PartitionCount: 1,
+		TotalLag:       30,
+	}}
+
+	merged := mergeGroupStatus(a, b).Status
+
+	if merged.Group != "payments-1a2b3c4d" {
+		t.Errorf("got group %q, want the first one", merged.Group)
+	}
+
+	if merged.TotalLag != 40 || merged.PartitionCount != 2 || len(merged.Partitions) != 2 {
+		t.Errorf("got total lag %d of %d partitions (%d listed), want 40 of 2", merged.TotalLag, merged.PartitionCount, len(merged.Partitions))
+	}
+
+	if merged.Status != "WARN" || merged.Complete != 0.5 || merged.MaxLag.Partition != 1 {
+		t.Errorf("got status %v, completeness %v and max lag partition %d, want the worst ones", merged.Status, merged.Complete, merged.MaxLag.Partition)
+	}
+
+	// The evaluations merged may be cached, so they're left as is.
+	if a.Status.TotalLag != 10 || len(a.Status.Partitions) != 1 {
+		t.Error("modified the merged evaluation")
+	}
+}
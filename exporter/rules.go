@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/shamil/burrow_exporter/internal/log"
+	"gopkg.in/yaml.v2"
+)
+
+// filterRule is a filter as written in the rules file.
+type filterRule struct {
+	Include string `yaml:"include"`
+	Exclude string `yaml:"exclude"`
+}
+
+func (r filterRule) compile() (NameFilter, error) {
+	var (
+		f   NameFilter
+		err error
+	)
+
+	if r.Include != "" {
+		if f.Include, err = AnchoredRegexp(r.Include); err != nil {
+			return f, fmt.Errorf("invalid include regex: %v", err)
+		}
+	}
+
+	if r.Exclude != "" {
+		if f.Exclude, err = AnchoredRegexp(r.Exclude); err != nil {
+			return f, fmt.Errorf("invalid exclude regex: %v", err)
+		}
+	}
+
+	return f, nil
+}
+
+// FilterRules select the clusters, consumer groups and topics whose
+// metrics are exported, on top of the command line filters.
+type FilterRules struct {
+	Clusters NameFilter
+	Groups   NameFilter
+	Topics   NameFilter
+}
+
+// LoadFilterRules reads the rules from a YAML file, e.g.
+//
+//	groups:
+//	  exclude: "console-consumer-.*"
+//	topics:
+//	  include: "orders\\..*"
+func LoadFilterRules(path string) (*FilterRules, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Clusters filterRule `yaml:"clusters"`
+		Groups   filterRule `yaml:"groups"`
+		Topics   filterRule `yaml:"topics"`
+	}
+
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing filter rules %v: %v", path, err)
+	}
+
+	rules := &FilterRules{}
+
+	for _, rule := range []struct {
+		name   string
+		rule   filterRule
+		filter *NameFilter
+	}{
+		{"clusters", file.Clusters, &rules.Clusters},
+		{"groups", file.Groups, &rules.Groups},
+		{"topics", file.Topics, &rules.Topics},
+	} {
+		if *rule.filter, err = rule.rule.compile(); err != nil {
+			return nil, fmt.Errorf("parsing filter rules %v: %v %v", path, rule.name, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// filterRulesFile reloads the filter rules whenever their file changes.
+type filterRulesFile struct {
+	path    string
+	modTime time.Time
+	rules   *FilterRules
+}
+
+func newFilterRulesFile(path string) (*filterRulesFile, error) {
+	f := &filterRulesFile{path: path}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *filterRulesFile) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	rules, err := LoadFilterRules(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.modTime = info.ModTime()
+	f.rules = rules
+
+	return nil
+}
+
+// current returns the rules, reloading them first when the file changed,
+// the previous rules are kept when they fail to reload.
+func (f *filterRulesFile) current() *FilterRules {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		log.With("err", err).Errorf("Failed checking filter rules (%v), keeping the previous ones", f.path)
+		return f.rules
+	}
+
+	if info.ModTime().Equal(f.modTime) {
+		return f.rules
+	}
+
+	if err := f.load(); err != nil {
+		log.With("err", err).Errorf("Failed reloading filter rules (%v), keeping the previous ones", f.path)
+		return f.rules
+	}
+
+	log.Infof("Reloaded filter rules (%v)", f.path)
+
+	return f.rules
+}
+
+// ReloadRules reloads the filter rules file, if any, even when unchanged,
+// the previous rules are kept when they fail to reload.
+func (c *Collector) ReloadRules() error {
+	if c.rulesFile == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.rulesFile.load(); err != nil {
+		return err
+	}
+
+	c.rules = *c.rulesFile.rules
+	log.Infof("Reloaded filter rules (%v)", c.rulesFile.path)
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package exporter
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// RefreshIntervals are the minimum time between two scrapes of a cluster,
+// the metrics of the previous scrape are served in between.
+type RefreshIntervals struct {
+	// Default applies to the clusters without their own interval, 0
+	// scrapes them on every collection.
+	Default  time.Duration
+	Clusters map[string]time.Duration
+}
+
+// ParseRefreshIntervals parses per cluster intervals in the
+// <cluster>=<duration> form, e.g. staging=2m.
+func ParseRefreshIntervals(defaultInterval time.Duration, intervals []string) (RefreshIntervals, error) {
+	r := RefreshIntervals{
+		Default:  defaultInterval,
+		Clusters: make(map[string]time.Duration),
+	}
+
+	for _, interval := range intervals {
+		i := strings.LastIndex(interval, "=")
+		if i <= 0 {
+			return r, fmt.Errorf("invalid cluster refresh interval %q, expected <cluster>=<duration>", interval)
+		}
+
+		d, err := time.ParseDuration(interval[i+1:])
+		if err != nil {
+			return r, fmt.Errorf("invalid cluster refresh interval %q: %v", interval, err)
+		}
+
+		r.Clusters[interval[:i]] = d
+	}
+
+	return r, nil
+}
+
+func (r RefreshIntervals) interval(cluster string) time.Duration {
+	if d, ok := r.Clusters[cluster]; ok {
+		return d
+	}
+
+	return r.Default
+}
+
+// clusterSnapshot holds the metrics of a cluster's latest scrape.
+type clusterSnapshot struct {
+	metrics []prometheus.Metric
+	// groups is the lag of the exported consumer groups, as served by the
+	// lag API.
+	groups []ConsumerGroupStatus
+	at     time.Time
+	// interval is the effective refresh interval, stretched from the
+	// configured one when adapting to slow scrapes.
+	interval time.Duration
+	// due is when the cluster is scraped again.
+	due time.Time
+}
+
+// servesStale tells whether the snapshot of the cluster is served in place
+// of a failed scrape, i.e. it's within the grace period.
+func (c *Collector) servesStale(cluster string, snapshot *clusterSnapshot, now time.Time) bool {
+	age := now.Sub(snapshot.at)
+	if age >= c.staleGrace {
+		return false
+	}
+
+	log.Warnf("Serving the metrics of cluster %v scraped %v ago", cluster, age)
+	return true
+}
+
+// refreshInterval returns the refresh interval of the cluster, stretched to
+// the adaptive factor times its scrape duration when longer, so the clusters
+// with many groups or a slow burrow are scraped less often instead of
+// keeping burrow busy all the time.
+func (c *Collector) refreshInterval(cluster string, took time.Duration) time.Duration {
+	interval := c.refreshIntervals.interval(cluster)
+
+	if adaptive := time.Duration(c.adaptiveRefresh * float64(took)); adaptive > interval {
+		return adaptive
+	}
+
+	return interval
+}
+
+// jitter returns a random duration below both max and the scrape jitter.
+func (c *Collector) jitter(max time.Duration) time.Duration {
+	if c.scrapeJitter < max {
+		max = c.scrapeJitter
+	}
+
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(c.random.Int63n(int64(max)))
+}
+
+// newRandom returns a random source seeded with the current time.
+func newRandom() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// groupCache keeps the latest status of each cluster's consumer groups for
+// the incremental refresh.
+type groupCache struct {
+	mutex    sync.Mutex
+	clusters map[string]*clusterGroups
+}
+
+// clusterGroups are the cached groups of a cluster, only used by the scrape
+// of that cluster.
+type clusterGroups struct {
+	cycle  int
+	groups map[string]*cachedGroup
+}
+
+type cachedGroup struct {
+	status    ConsumerGroupStatus
+	fetchedAt time.Time
+	// lastCommit is the timestamp of the group's latest commit.
+	lastCommit int64
+	// active is whether the group committed between its last two fetches.
+	active bool
+}
+
+// invalidate forgets the cached status of the group, or of all the groups
+// of the cluster when group is empty, or of all the clusters when cluster
+// is empty too.
+func (gc *groupCache) invalidate(cluster, group string) {
+	gc.mutex.Lock()
+	defer gc.mutex.Unlock()
+
+	switch {
+	case cluster == "":
+		gc.clusters = nil
+	case group == "":
+		delete(gc.clusters, cluster)
+	case gc.clusters[cluster] != nil:
+		delete(gc.clusters[cluster].groups, group)
+	}
+}
+
+// forCluster returns the cached groups of the cluster, counting another
+// refresh cycle.
+func (gc *groupCache) forCluster(cluster string) *clusterGroups {
+	gc.mutex.Lock()
+	defer gc.mutex.Unlock()
+
+	if gc.clusters == nil {
+		gc.clusters = make(map[string]*clusterGroups)
+	}
+
+	groups, ok := gc.clusters[cluster]
+	if !ok {
+		groups = &clusterGroups{groups: make(map[string]*cachedGroup)}
+		gc.clusters[cluster] = groups
+	} else {
+		groups.cycle++
+	}
+
+	return groups
+}
+
+// reusable returns the cached status of the group, unless it's unknown,
+// was active when last fetched, or was fetched more than maxAge ago, as an
+// idle group may start committing again. A zero maxAge doesn't bound it.
+func (cg *clusterGroups) reusable(group string, now time.Time, maxAge time.Duration) (*ConsumerGroupStatusResp, bool) {
+	cached, ok := cg.groups[group]
+	if !ok || cached.active || (maxAge > 0 && now.Sub(cached.fetchedAt) >= maxAge) {
+		return nil, false
+	}
+
+	// The partitions get filtered in place, so hand out a copy.
+	resp := &ConsumerGroupStatusResp{Status: cached.status}
+	resp.Status.Partitions = append([]Partition(nil), cached.status.Partitions...)
+
+	return resp, true
+}
+
+// update caches the freshly fetched status of the group.
+func (cg *clusterGroups) update(group string, resp *ConsumerGroupStatusResp, now time.Time) {
+	cached := &cachedGroup{status: resp.Status, fetchedAt: now}
+	cached.status.Partitions = append([]Partition(nil), resp.Status.Partitions...)
+
+	for _, partition := range resp.Status.Partitions {
+		if partition.End.Timestamp > cached.lastCommit {
+			cached.lastCommit = partition.End.Timestamp
+		}
+	}
+
+	previous, ok := cg.groups[group]
+	cached.active = !ok || cached.lastCommit != previous.lastCommit
+	cg.groups[group] = cached
+}
+
+// retain forgets the groups not in the consumer list anymore.
+func (cg *clusterGroups) retain(groups []string) {
+	current := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		current[group] = true
+	}
+
+	for group := range cg.groups {
+		if !current[group] {
+			delete(cg.groups, group)
+		}
+	}
+}
+
+// scrapeTimeoutHeader is set by prometheus to its scrape timeout.
+const scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+
+// ScrapeTimeoutHandler wraps the metrics handler, bounding the requests to
+// burrow by prometheus' scrape timeout less the offset, so the metrics
+// gathered by then are returned before prometheus gives up.
+func (c *Collector) ScrapeTimeoutHandler(offset time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get(scrapeTimeoutHeader); header != "" {
+			seconds, err := strconv.ParseFloat(header, 64)
+			if err != nil || seconds <= 0 {
+				log.Warnf("Invalid %v header %q, ignoring", scrapeTimeoutHeader, header)
+			} else {
+				c.setDeadline(time.Now().Add(time.Duration(seconds*float64(time.Second)) - offset))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setDeadline sets the deadline of the next scrape.
+func (c *Collector) setDeadline(deadline time.Time) {
+	c.deadlineMutex.Lock()
+	defer c.deadlineMutex.Unlock()
+
+	c.deadline = deadline
+}
+
+// takeDeadline returns the deadline of the scrape, if any, clearing it.
+func (c *Collector) takeDeadline() time.Time {
+	c.deadlineMutex.Lock()
+	defer c.deadlineMutex.Unlock()
+
+	deadline := c.deadline
+	c.deadline = time.Time{}
+
+	return deadline
+}
+
+// Refresh scrapes burrow right away, fetching the consumer group again, or
+// the whole cluster when group is empty, or all the clusters when cluster is
+// empty too, bypassing the caches and refresh intervals. The other clusters
+// are served from their snapshots as usual.
+func (c *Collector) Refresh(cluster, group string) {
+	c.mutex.Lock()
+
+	for name, snapshot := range c.snapshots {
+		if cluster == "" || name == cluster {
+			snapshot.due = time.Time{}
+		}
+	}
+
+	c.groupCache.invalidate(cluster, group)
+	c.client.Invalidate(cluster, group)
+	c.mutex.Unlock()
+
+	collectAll(c)
+}
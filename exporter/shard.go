@@ -0,0 +1,42 @@
+package exporter
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Shard is the part of the consumer groups and topics scraped by one of
+// several exporter replicas, so they can split the load on burrow.
+type Shard struct {
+	// Index of the replica, from 0 to Count-1.
+	Index int
+	Count int
+}
+
+// owns tells whether the item identified by the key parts belongs to the
+// shard. Items are assigned with a consistent hash, so changing the number
+// of shards only moves the items of the added or removed ones.
+func (s Shard) owns(parts ...string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(parts, "\x00")))
+
+	return jumpHash(h.Sum64(), s.Count) == s.Index
+}
+
+// jumpHash is the jump consistent hash of the key into the buckets, see
+// https://arxiv.org/abs/1406.2294.
+func jumpHash(key uint64, buckets int) int {
+	b, j := int64(-1), int64(0)
+
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
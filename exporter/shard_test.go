@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShardOwns(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+	}{
+		{name: "unsharded", count: 0},
+		{name: "single shard", count: 1},
+		{name: "two shards", count: 2},
+		{name: "five shards", count: 5},
+		{name: "sixteen shards", count: 16},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shards := test.count
+			if shards < 1 {
+				shards = 1
+			}
+
+			owned := make([]int, shards)
+			for i := 0; i < 1000; i++ {
+				group := fmt.Sprintf("group-%d", i)
+
+				owners := 0
+				for index := 0; index < shards; index++ {
+					if (Shard{Index: index, Count: test.count}).owns("cluster", group) {
+						owners++
+						owned[index]++
+					}
+				}
+
+				if owners != 1 {
+					t.Fatalf("%v is owned by %d shards, want 1", group, owners)
+				}
+			}
+
+			// The hash should spread the groups about evenly.
+			for index, n := range owned {
+				if n < 1000/shards/2 {
+					t.Errorf("shard %d owns %d groups out of 1000", index, n)
+				}
+			}
+		})
+	}
+}
+
+func TestJumpHashConsistency(t *testing.T) {
+	tests := []struct {
+		from, to int
+	}{
+		{from: 1, to: 2},
+		{from: 3, to: 4},
+		{from: 4, to: 5},
+		{from: 10, to: 11},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d to %d", test.from, test.to), func(t *testing.T) {
+			for key := uint64(0); key < 10000; key++ {
+				before, after := jumpHash(key, test.from), jumpHash(key, test.to)
+
+				if before < 0 || before >= test.from || after < 0 || after >= test.to {
+					t.Fatalf("key %d is out of the buckets: %d of %d, %d of %d", key, before, test.from, after, test.to)
+				}
+
+				// Adding buckets only moves keys to the added ones.
+				if after != before && after < test.from {
+					t.Fatalf("key %d moved from bucket %d to %d", key, before, after)
+				}
+			}
+		})
+	}
+}
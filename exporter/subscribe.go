@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// subscriptionBuffer is how many updates a subscriber may fall behind by,
+// the further ones are dropped rather than holding the scrape.
+const subscriptionBuffer = 1024
+
+// GroupUpdate is the lag of a consumer group, sent to the subscribers when
+// its cluster is refreshed.
+type GroupUpdate struct {
+	Cluster   string    `json:"cluster"`
+	Group     string    `json:"group"`
+	Status    string    `json:"status"`
+	TotalLag  int64     `json:"totallag"`
+	ScrapedAt time.Time `json:"scraped_at"`
+}
+
+// Subscription receives the updates of the consumer groups, until it's
+// closed.
+type Subscription struct {
+	// Updates is closed along with the subscription.
+	Updates <-chan GroupUpdate
+
+	updates chan GroupUpdate
+	subs    *subscriptions
+}
+
+// Close stops the updates of the subscription.
+func (s *Subscription) Close() {
+	s.subs.remove(s)
+}
+
+type subscriptions struct {
+	mutex  sync.Mutex
+	subs   map[*Subscription]bool
+	closed bool
+
+	dropped prometheus.Counter
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		subs: make(map[*Subscription]bool),
+		dropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "burrow_exporter_subscription_updates_dropped_total",
+			Help: "Total number of consumer group updates dropped as their subscriber fell behind.",
+		}),
+	}
+}
+
+func (s *subscriptions) add() *Subscription {
+	updates := make(chan GroupUpdate, subscriptionBuffer)
+	sub := &Subscription{Updates: updates, updates: updates, subs: s}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		close(updates)
+		return sub
+	}
+
+	s.subs[sub] = true
+	return sub
+}
+
+func (s *subscriptions) remove(sub *Subscription) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.subs[sub] {
+		delete(s.subs, sub)
+		close(sub.updates)
+	}
+}
+
+func (s *subscriptions) send(updates []GroupUpdate) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for sub := range s.subs {
+		for _, update := range updates {
+			select {
+			case sub.updates <- update:
+			default:
+				s.dropped.Inc()
+			}
+		}
+	}
+}
+
+func (s *subscriptions) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for sub := range s.subs {
+		close(sub.updates)
+	}
+
+	s.subs = nil
+	s.closed = true
+}
+
+// Subscribe returns a subscription to the updates of the consumer groups of
+// every refreshed cluster.
+func (c *Collector) Subscribe() *Subscription {
+	return c.subscriptions.add()
+}
+
+// CloseSubscriptions closes the subscriptions, and the ones made later, e.g.
+// so their streams end when shutting down.
+func (c *Collector) CloseSubscriptions() {
+	c.subscriptions.close()
+}
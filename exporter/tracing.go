@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the client.
+const tracerName = "github.com/shamil/burrow_exporter/exporter"
+
+// The attributes of the spans of the burrow API calls.
+const (
+	attrEndpoint = attribute.Key("burrow.endpoint")
+	attrKind     = attribute.Key("burrow.endpoint.kind")
+	attrCluster  = attribute.Key("burrow.cluster")
+	attrGroup    = attribute.Key("burrow.group")
+	attrCached   = attribute.Key("burrow.cached")
+)
+
+// WithTracerProvider traces the calls to the burrow API with a span each,
+// having the endpoint, cluster and consumer group as attributes, and a
+// child span per HTTP request made for it across the failovers and
+// retries. The calls made by a collector's scrape are children of its span. Their trace context is propagated to burrow in the traceparent
+// header, e.g. to follow them through proxies. It must come after the
+// options replacing the transport, e.g. WithReplay.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(bc *BurrowClient) {
+		bc.tracer = tp.Tracer(tracerName)
+		bc.client.Transport = otelhttp.NewTransport(bc.roundTripper(),
+			otelhttp.WithTracerProvider(tp),
+			otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})),
+		)
+	}
+}
+
+// startSpan starts the span of a call to the API endpoint, whose cluster and
+// consumer group are taken from its path, i.e. /kafka/<cluster>/consumer/<group>.
+func (bc *BurrowClient) startSpan(method string, kind endpointKind, endpoint string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attrEndpoint.String(endpoint),
+		attrKind.String(kind.String()),
+	}
+
+	parts := strings.Split(strings.Trim(endpoint, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "kafka" {
+		attrs = append(attrs, attrCluster.String(parts[1]))
+	}
+	if len(parts) >= 4 && parts[2] == "consumer" {
+		attrs = append(attrs, attrGroup.String(parts[3]))
+	}
+
+	bc.mutex.Lock()
+	parent := trace.ContextWithSpanContext(bc.ctx, bc.scrapeSpan)
+	bc.mutex.Unlock()
+
+	return bc.tracer.Start(parent, "burrow "+method+" "+kind.String(), trace.WithAttributes(attrs...))
+}
+
+// startScrapeSpan starts the span of a scrape, the parent of the spans of
+// the calls made until endScrapeSpan.
+func (bc *BurrowClient) startScrapeSpan() trace.Span {
+	_, span := bc.tracer.Start(bc.ctx, "burrow_exporter scrape")
+
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	bc.scrapeSpan = span.SpanContext()
+	return span
+}
+
+// endScrapeSpan ends the span of a scrape, failed when burrow wasn't
+// healthy.
+func (bc *BurrowClient) endScrapeSpan(span trace.Span, healthy bool) {
+	bc.mutex.Lock()
+	bc.scrapeSpan = trace.SpanContext{}
+	bc.mutex.Unlock()
+
+	if !healthy {
+		span.SetStatus(codes.Error, "burrow is unhealthy")
+	}
+
+	span.End()
+}
+
+// endSpan ends the span of a call, failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,64 @@
+package exporter_test
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+func TestCollectSpans(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(1, 2, 1))
+	defer mock.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer tp.Shutdown(context.Background())
+
+	client := mock.Client(3, exporter.WithTracerProvider(tp))
+	defer client.Close()
+
+	gather(t, exporter.NewCollector(client, ""))
+
+	spans := recorder.Ended()
+
+	var scrape sdktrace.ReadOnlySpan
+
+	for _, span := range spans {
+		if span.Name() == "burrow_exporter scrape" {
+			scrape = span
+		}
+	}
+
+	if scrape == nil {
+		t.Fatal("the scrape wasn't traced")
+	}
+
+	if scrape.Parent().IsValid() {
+		t.Error("the scrape span isn't a root span")
+	}
+
+	// The calls, the health check included, are children of the scrape.
+	calls := make(map[string]int)
+	for _, span := range spans {
+		if span.Parent().SpanID() == scrape.SpanContext().SpanID() {
+			calls[span.Name()]++
+		}
+	}
+
+	for _, name := range []string{"burrow GET admin", "burrow GET clusters", "burrow GET consumers", "burrow GET status"} {
+		if calls[name] == 0 {
+			t.Errorf("no %q span is a child of the scrape, got %v", name, calls)
+		}
+	}
+
+	for _, span := range spans {
+		if span.SpanContext().TraceID() != scrape.SpanContext().TraceID() {
+			t.Errorf("span %v isn't in the trace of the scrape", span.Name())
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/lagpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCServer returns the gRPC server of the lag, over TLS and requiring
+// basic auth in the authorization metadata when the web config enables them.
+func newGRPCServer(c *exporter.Collector, config *webConfig) (*grpc.Server, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	if config != nil {
+		opts = append(opts,
+			grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				if err := config.authenticateGRPC(ctx); err != nil {
+					return nil, err
+				}
+
+				return handler(ctx, req)
+			}),
+			grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				if err := config.authenticateGRPC(stream.Context()); err != nil {
+					return err
+				}
+
+				return handler(srv, stream)
+			}),
+		)
+	}
+
+	server := grpc.NewServer(opts...)
+	lagpb.RegisterLagServer(server, &lagServer{collector: c})
+
+	return server, nil
+}
+
+// authenticateGRPC checks the basic auth credentials of the call, given in
+// its authorization metadata like the HTTP header, when users are configured.
+func (c *webConfig) authenticateGRPC(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	// Parse the credentials like the HTTP server does.
+	r := http.Request{Header: http.Header{"Authorization": md.Get("authorization")}}
+	user, password, _ := r.BasicAuth()
+
+	if ok, _ := c.checkPassword(user, password); !ok {
+		return status.Error(codes.Unauthenticated, "invalid basic auth credentials")
+	}
+
+	return nil
+}
+
+// serveGRPC serves the gRPC server on address.
+func serveGRPC(server *grpc.Server, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(listener)
+}
+
+// lagServer serves the lag of the consumer groups over gRPC.
+type lagServer struct {
+	collector *exporter.Collector
+}
+
+func (s *lagServer) ListGroups(ctx context.Context, req *lagpb.ListGroupsRequest) (*lagpb.ListGroupsResponse, error) {
+	resp := &lagpb.ListGroupsResponse{}
+
+	for _, cluster := range s.collector.Lag() {
+		if !oneOf(req.Clusters, cluster.Cluster) {
+			continue
+		}
+
+		scrapedAt, err := ptypes.TimestampProto(cluster.ScrapedAt)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		for _, group := range cluster.Groups {
+			resp.Groups = append(resp.Groups, &lagpb.GroupSummary{
+				Cluster:   cluster.Cluster,
+				Group:     group.Group,
+				Status:    group.Status,
+				TotalLag:  group.TotalLag,
+				ScrapedAt: scrapedAt,
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *lagServer) GetGroupLag(ctx context.Context, req *lagpb.GetGroupLagRequest) (*lagpb.GroupLag, error) {
+	lag, found, err := s.groupLag(req.Cluster, req.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "consumer group %v of cluster %v not found", req.Group, req.Cluster)
+	}
+
+	return lag, nil
+}
+
+func (s *lagServer) WatchGroup(req *lagpb.WatchGroupRequest, stream lagpb.Lag_WatchGroupServer) error {
+	// Subscribe before taking the current lag, so no refresh is missed in
+	// between.
+	sub := s.collector.Subscribe()
+	defer sub.Close()
+
+	send := func() error {
+		lag, found, err := s.groupLag(req.Cluster, req.Group)
+		if err != nil || !found {
+			return err
+		}
+
+		return stream.Send(lag)
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case update, ok := <-sub.Updates:
+			if !ok {
+				return status.Error(codes.Unavailable, "shutting down")
+			}
+
+			if update.Cluster == req.Cluster && update.Group == req.Group {
+				if err := send(); err != nil {
+					return err
+				}
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// groupLag returns the lag of the consumer group as of the latest scrape.
+func (s *lagServer) groupLag(cluster, group string) (lag *lagpb.GroupLag, found bool, err error) {
+	for _, clusterLag := range s.collector.Lag() {
+		if clusterLag.Cluster != cluster {
+			continue
+		}
+
+		for _, status := range clusterLag.Groups {
+			if status.Group != group {
+				continue
+			}
+
+			scrapedAt, err := ptypes.TimestampProto(clusterLag.ScrapedAt)
+			if err != nil {
+				return nil, false, err
+			}
+
+			lag = &lagpb.GroupLag{
+				Cluster:   cluster,
+				Group:     group,
+				Status:    status.Status,
+				Complete:  float64(status.Complete),
+				TotalLag:  status.TotalLag,
+				MaxLag:    partitionLag(status.MaxLag),
+				ScrapedAt: scrapedAt,
+			}
+
+			for _, partition := range status.Partitions {
+				lag.Partitions = append(lag.Partitions, partitionLag(partition))
+			}
+
+			return lag, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+func partitionLag(partition exporter.Partition) *lagpb.PartitionLag {
+	return &lagpb.PartitionLag{
+		Topic:       partition.Topic,
+		Partition:   partition.Partition,
+		Status:      partition.Status,
+		CurrentLag:  partition.CurrentLag,
+		StartOffset: partition.Start.Offset,
+		EndOffset:   partition.End.Offset,
+		Complete:    float64(partition.Complete),
+		Owner:       partition.Owner,
+		ClientId:    partition.ClientID,
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// influxConfig is where to write the metrics to, with the database,
+// retention policy and credentials of the v1 API, or the org, bucket and
+// token of the v2 one.
+type influxConfig struct {
+	URL        string
+	APIVersion string
+
+	Database  string
+	Retention string
+	Username  string
+	Password  string
+
+	Org    string
+	Bucket string
+	Token  string
+}
+
+// influxWriter writes the metrics in InfluxDB's line protocol, as a
+// measurement per metric with its labels as tags.
+type influxWriter struct {
+	config   influxConfig
+	client   *http.Client
+	gatherer prometheus.Gatherer
+}
+
+func newInfluxWriter(config influxConfig, gatherer prometheus.Gatherer) (*influxWriter, error) {
+	switch config.APIVersion {
+	case "1":
+		if config.Database == "" {
+			return nil, fmt.Errorf("the database is required by the v1 API")
+		}
+	case "2":
+		if config.Org == "" || config.Bucket == "" {
+			return nil, fmt.Errorf("the org and bucket are required by the v2 API")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported API version %q", config.APIVersion)
+	}
+
+	return &influxWriter{
+		config:   config,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		gatherer: gatherer,
+	}, nil
+}
+
+// run gathers the metrics, scraping burrow when due, and writes them every
+// interval, until ctx is done.
+func (w *influxWriter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.write(ctx); err != nil && ctx.Err() == nil {
+			log.With("err", err).Error("Failed writing the metrics to InfluxDB")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *influxWriter) write(ctx context.Context) error {
+	families, err := w.gatherer.Gather()
+	if err != nil {
+		log.With("err", err).Warn("Failed gathering some of the metrics to write to InfluxDB")
+	}
+
+	var buf bytes.Buffer
+	writeLineProtocol(&buf, families, time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.writeURL(), &buf)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.config.APIVersion == "2" {
+		req.Header.Set("Authorization", "Token "+w.config.Token)
+	} else if w.config.Username != "" {
+		req.SetBasicAuth(w.config.Username, w.config.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %v: %s", resp.Status, body)
+	}
+
+	return nil
+}
+
+func (w *influxWriter) writeURL() string {
+	params := url.Values{"precision": {"ms"}}
+	path := "/write"
+
+	if w.config.APIVersion == "2" {
+		path = "/api/v2/write"
+		params.Set("org", w.config.Org)
+		params.Set("bucket", w.config.Bucket)
+	} else {
+		params.Set("db", w.config.Database)
+		if w.config.Retention != "" {
+			params.Set("rp", w.config.Retention)
+		}
+	}
+
+	return strings.TrimSuffix(w.config.URL, "/") + path + "?" + params.Encode()
+}
+
+// writeLineProtocol writes a point per series, with millisecond precision.
+// The histograms and summaries get their count, sum and buckets or
+// quantiles as fields.
+func writeLineProtocol(buf *bytes.Buffer, families []*dto.MetricFamily, now time.Time) {
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			fields := metricFields(family.GetType(), metric)
+			if len(fields) == 0 {
+				continue
+			}
+
+			buf.WriteString(influxEscaper.Replace(family.GetName()))
+			for _, label := range metric.Label {
+				// InfluxDB doesn't accept empty tag values.
+				if label.GetValue() == "" {
+					continue
+				}
+
+				fmt.Fprintf(buf, ",%s=%s", influxEscaper.Replace(label.GetName()), influxEscaper.Replace(label.GetValue()))
+			}
+
+			for i, field := range fields {
+				separator := ","
+				if i == 0 {
+					separator = " "
+				}
+
+				fmt.Fprintf(buf, "%s%s=%s", separator, influxEscaper.Replace(field.name), strconv.FormatFloat(field.value, 'g', -1, 64))
+			}
+
+			timestamp := now.UnixNano() / int64(time.Millisecond)
+			if metric.TimestampMs != nil {
+				timestamp = metric.GetTimestampMs()
+			}
+
+			fmt.Fprintf(buf, " %d\n", timestamp)
+		}
+	}
+}
+
+// influxEscaper escapes the measurements, tags and field keys.
+var influxEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+type influxField struct {
+	name  string
+	value float64
+}
+
+// metricFields returns the fields of the series, leaving out the NaN and
+// infinite values InfluxDB doesn't accept.
+func metricFields(metricType dto.MetricType, metric *dto.Metric) []influxField {
+	var fields []influxField
+	add := func(name string, value float64) {
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			fields = append(fields, influxField{name: name, value: value})
+		}
+	}
+
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		add("value", metric.GetCounter().GetValue())
+	case dto.MetricType_GAUGE:
+		add("value", metric.GetGauge().GetValue())
+	case dto.MetricType_UNTYPED:
+		add("value", metric.GetUntyped().GetValue())
+	case dto.MetricType_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		add("count", float64(histogram.GetSampleCount()))
+		add("sum", histogram.GetSampleSum())
+		for _, bucket := range histogram.Bucket {
+			add(strconv.FormatFloat(bucket.GetUpperBound(), 'g', -1, 64), float64(bucket.GetCumulativeCount()))
+		}
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		add("count", float64(summary.GetSampleCount()))
+		add("sum", summary.GetSampleSum())
+		for _, quantile := range summary.Quantile {
+			add(strconv.FormatFloat(quantile.GetQuantile(), 'g', -1, 64), quantile.GetValue())
+		}
+	}
+
+	return fields
+}
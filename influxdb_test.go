@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWriteLineProtocol(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+
+	tests := []struct {
+		name    string
+		collect func(registry *prometheus.Registry)
+		want    string
+	}{
+		{
+			name: "gauge",
+			collect: func(registry *prometheus.Registry) {
+				lag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "lag", Help: "Lag."}, []string{"cluster", "group"})
+				lag.WithLabelValues("local", "orders").Set(42)
+				registry.MustRegister(lag)
+			},
+			want: "lag,cluster=local,group=orders value=42 1500000000000\n",
+		},
+		{
+			name: "escaped",
+			collect: func(registry *prometheus.Registry) {
+				lag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "lag", Help: "Lag."}, []string{"group"})
+				lag.WithLabelValues("a,b=c d").Set(1)
+				registry.MustRegister(lag)
+			},
+			want: `lag,group=a\,b\=c\ d value=1 1500000000000` + "\n",
+		},
+		{
+			name: "empty tag",
+			collect: func(registry *prometheus.Registry) {
+				lag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "lag", Help: "Lag."}, []string{"cluster", "group"})
+				lag.WithLabelValues("", "orders").Set(1.5)
+				registry.MustRegister(lag)
+			},
+			want: "lag,group=orders value=1.5 1500000000000\n",
+		},
+		{
+			name: "not a number",
+			collect: func(registry *prometheus.Registry) {
+				registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: "nan", Help: "NaN."}, math.NaN))
+				registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: "inf", Help: "Inf."}, func() float64 { return math.Inf(1) }))
+			},
+			want: "",
+		},
+		{
+			name: "histogram",
+			collect: func(registry *prometheus.Registry) {
+				histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "duration", Help: "Duration.", Buckets: []float64{0.5, 1}})
+				histogram.Observe(0.25)
+				histogram.Observe(0.75)
+				registry.MustRegister(histogram)
+			},
+			want: "duration count=2,sum=1,0.5=1,1=2 1500000000000\n",
+		},
+		{
+			name: "timestamped",
+			collect: func(registry *prometheus.Registry) {
+				registry.MustRegister(timestampedCollector{})
+			},
+			want: "offset value=7 1400000000000\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			test.collect(registry)
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			writeLineProtocol(&buf, families, now)
+
+			if got := buf.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+type timestampedCollector struct{}
+
+var offsetDesc = prometheus.NewDesc("offset", "Offset.", nil, nil)
+
+func (timestampedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- offsetDesc
+}
+
+func (timestampedCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.NewMetricWithTimestamp(time.Unix(1400000000, 0), prometheus.MustNewConstMetric(offsetDesc, prometheus.GaugeValue, 7))
+}
+
+func TestInfluxWriteURL(t *testing.T) {
+	tests := []struct {
+		config influxConfig
+		want   string
+	}{
+		{
+			config: influxConfig{URL: "http://influx:8086/", APIVersion: "1", Database: "kafka"},
+			want:   "http://influx:8086/write?db=kafka&precision=ms",
+		},
+		{
+			config: influxConfig{URL: "http://influx:8086", APIVersion: "1", Database: "kafka", Retention: "week"},
+			want:   "http://influx:8086/write?db=kafka&precision=ms&rp=week",
+		},
+		{
+			config: influxConfig{URL: "http://influx:8086", APIVersion: "2", Org: "acme", Bucket: "kafka"},
+			want:   "http://influx:8086/api/v2/write?bucket=kafka&org=acme&precision=ms",
+		},
+	}
+
+	for _, test := range tests {
+		writer, err := newInfluxWriter(test.config, prometheus.NewRegistry())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := writer.writeURL(); got != test.want {
+			t.Errorf("got %v, want %v", got, test.want)
+		}
+	}
+}
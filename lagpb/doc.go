@@ -0,0 +1,5 @@
+// Package lagpb is the gRPC service serving the lag of the consumer groups,
+// generated from lag.proto.
+package lagpb
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. lag.proto
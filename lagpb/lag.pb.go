@@ -0,0 +1,710 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: lag.proto
+
+// The lag of the consumer groups as of the exporter's latest scrape of
+// burrow, the same data as served by /api/v1/lag.
+
+package lagpb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type ListGroupsRequest struct {
+	// clusters narrows the groups down to these clusters, all when empty.
+	Clusters             []string `protobuf:"bytes,1,rep,name=clusters,proto3" json:"clusters,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListGroupsRequest) Reset()         { *m = ListGroupsRequest{} }
+func (m *ListGroupsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListGroupsRequest) ProtoMessage()    {}
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{0}
+}
+
+func (m *ListGroupsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListGroupsRequest.Unmarshal(m, b)
+}
+func (m *ListGroupsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListGroupsRequest.Marshal(b, m, deterministic)
+}
+func (m *ListGroupsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListGroupsRequest.Merge(m, src)
+}
+func (m *ListGroupsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListGroupsRequest.Size(m)
+}
+func (m *ListGroupsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListGroupsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListGroupsRequest proto.InternalMessageInfo
+
+func (m *ListGroupsRequest) GetClusters() []string {
+	if m != nil {
+		return m.Clusters
+	}
+	return nil
+}
+
+type ListGroupsResponse struct {
+	Groups               []*GroupSummary `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ListGroupsResponse) Reset()         { *m = ListGroupsResponse{} }
+func (m *ListGroupsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListGroupsResponse) ProtoMessage()    {}
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{1}
+}
+
+func (m *ListGroupsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListGroupsResponse.Unmarshal(m, b)
+}
+func (m *ListGroupsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListGroupsResponse.Marshal(b, m, deterministic)
+}
+func (m *ListGroupsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListGroupsResponse.Merge(m, src)
+}
+func (m *ListGroupsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListGroupsResponse.Size(m)
+}
+func (m *ListGroupsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListGroupsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListGroupsResponse proto.InternalMessageInfo
+
+func (m *ListGroupsResponse) GetGroups() []*GroupSummary {
+	if m != nil {
+		return m.Groups
+	}
+	return nil
+}
+
+type GroupSummary struct {
+	Cluster              string               `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group                string               `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Status               string               `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	TotalLag             int64                `protobuf:"varint,4,opt,name=total_lag,json=totalLag,proto3" json:"total_lag,omitempty"`
+	ScrapedAt            *timestamp.Timestamp `protobuf:"bytes,5,opt,name=scraped_at,json=scrapedAt,proto3" json:"scraped_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GroupSummary) Reset()         { *m = GroupSummary{} }
+func (m *GroupSummary) String() string { return proto.CompactTextString(m) }
+func (*GroupSummary) ProtoMessage()    {}
+func (*GroupSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{2}
+}
+
+func (m *GroupSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GroupSummary.Unmarshal(m, b)
+}
+func (m *GroupSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GroupSummary.Marshal(b, m, deterministic)
+}
+func (m *GroupSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GroupSummary.Merge(m, src)
+}
+func (m *GroupSummary) XXX_Size() int {
+	return xxx_messageInfo_GroupSummary.Size(m)
+}
+func (m *GroupSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_GroupSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GroupSummary proto.InternalMessageInfo
+
+func (m *GroupSummary) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *GroupSummary) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *GroupSummary) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *GroupSummary) GetTotalLag() int64 {
+	if m != nil {
+		return m.TotalLag
+	}
+	return 0
+}
+
+func (m *GroupSummary) GetScrapedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ScrapedAt
+	}
+	return nil
+}
+
+type GetGroupLagRequest struct {
+	Cluster              string   `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group                string   `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetGroupLagRequest) Reset()         { *m = GetGroupLagRequest{} }
+func (m *GetGroupLagRequest) String() string { return proto.CompactTextString(m) }
+func (*GetGroupLagRequest) ProtoMessage()    {}
+func (*GetGroupLagRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{3}
+}
+
+func (m *GetGroupLagRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetGroupLagRequest.Unmarshal(m, b)
+}
+func (m *GetGroupLagRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetGroupLagRequest.Marshal(b, m, deterministic)
+}
+func (m *GetGroupLagRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetGroupLagRequest.Merge(m, src)
+}
+func (m *GetGroupLagRequest) XXX_Size() int {
+	return xxx_messageInfo_GetGroupLagRequest.Size(m)
+}
+func (m *GetGroupLagRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetGroupLagRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetGroupLagRequest proto.InternalMessageInfo
+
+func (m *GetGroupLagRequest) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *GetGroupLagRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+type WatchGroupRequest struct {
+	Cluster              string   `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group                string   `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchGroupRequest) Reset()         { *m = WatchGroupRequest{} }
+func (m *WatchGroupRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchGroupRequest) ProtoMessage()    {}
+func (*WatchGroupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{4}
+}
+
+func (m *WatchGroupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchGroupRequest.Unmarshal(m, b)
+}
+func (m *WatchGroupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchGroupRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchGroupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchGroupRequest.Merge(m, src)
+}
+func (m *WatchGroupRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchGroupRequest.Size(m)
+}
+func (m *WatchGroupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchGroupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchGroupRequest proto.InternalMessageInfo
+
+func (m *WatchGroupRequest) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *WatchGroupRequest) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+type GroupLag struct {
+	Cluster              string               `protobuf:"bytes,1,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	Group                string               `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	Status               string               `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Complete             float64              `protobuf:"fixed64,4,opt,name=complete,proto3" json:"complete,omitempty"`
+	TotalLag             int64                `protobuf:"varint,5,opt,name=total_lag,json=totalLag,proto3" json:"total_lag,omitempty"`
+	MaxLag               *PartitionLag        `protobuf:"bytes,6,opt,name=max_lag,json=maxLag,proto3" json:"max_lag,omitempty"`
+	Partitions           []*PartitionLag      `protobuf:"bytes,7,rep,name=partitions,proto3" json:"partitions,omitempty"`
+	ScrapedAt            *timestamp.Timestamp `protobuf:"bytes,8,opt,name=scraped_at,json=scrapedAt,proto3" json:"scraped_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GroupLag) Reset()         { *m = GroupLag{} }
+func (m *GroupLag) String() string { return proto.CompactTextString(m) }
+func (*GroupLag) ProtoMessage()    {}
+func (*GroupLag) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{5}
+}
+
+func (m *GroupLag) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GroupLag.Unmarshal(m, b)
+}
+func (m *GroupLag) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GroupLag.Marshal(b, m, deterministic)
+}
+func (m *GroupLag) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GroupLag.Merge(m, src)
+}
+func (m *GroupLag) XXX_Size() int {
+	return xxx_messageInfo_GroupLag.Size(m)
+}
+func (m *GroupLag) XXX_DiscardUnknown() {
+	xxx_messageInfo_GroupLag.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GroupLag proto.InternalMessageInfo
+
+func (m *GroupLag) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+func (m *GroupLag) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *GroupLag) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *GroupLag) GetComplete() float64 {
+	if m != nil {
+		return m.Complete
+	}
+	return 0
+}
+
+func (m *GroupLag) GetTotalLag() int64 {
+	if m != nil {
+		return m.TotalLag
+	}
+	return 0
+}
+
+func (m *GroupLag) GetMaxLag() *PartitionLag {
+	if m != nil {
+		return m.MaxLag
+	}
+	return nil
+}
+
+func (m *GroupLag) GetPartitions() []*PartitionLag {
+	if m != nil {
+		return m.Partitions
+	}
+	return nil
+}
+
+func (m *GroupLag) GetScrapedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ScrapedAt
+	}
+	return nil
+}
+
+type PartitionLag struct {
+	Topic                string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Partition            int32    `protobuf:"varint,2,opt,name=partition,proto3" json:"partition,omitempty"`
+	Status               string   `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	CurrentLag           int64    `protobuf:"varint,4,opt,name=current_lag,json=currentLag,proto3" json:"current_lag,omitempty"`
+	StartOffset          int64    `protobuf:"varint,5,opt,name=start_offset,json=startOffset,proto3" json:"start_offset,omitempty"`
+	EndOffset            int64    `protobuf:"varint,6,opt,name=end_offset,json=endOffset,proto3" json:"end_offset,omitempty"`
+	Complete             float64  `protobuf:"fixed64,7,opt,name=complete,proto3" json:"complete,omitempty"`
+	Owner                string   `protobuf:"bytes,8,opt,name=owner,proto3" json:"owner,omitempty"`
+	ClientId             string   `protobuf:"bytes,9,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PartitionLag) Reset()         { *m = PartitionLag{} }
+func (m *PartitionLag) String() string { return proto.CompactTextString(m) }
+func (*PartitionLag) ProtoMessage()    {}
+func (*PartitionLag) Descriptor() ([]byte, []int) {
+	return fileDescriptor_932644ad334c12e5, []int{6}
+}
+
+func (m *PartitionLag) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PartitionLag.Unmarshal(m, b)
+}
+func (m *PartitionLag) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PartitionLag.Marshal(b, m, deterministic)
+}
+func (m *PartitionLag) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PartitionLag.Merge(m, src)
+}
+func (m *PartitionLag) XXX_Size() int {
+	return xxx_messageInfo_PartitionLag.Size(m)
+}
+func (m *PartitionLag) XXX_DiscardUnknown() {
+	xxx_messageInfo_PartitionLag.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PartitionLag proto.InternalMessageInfo
+
+func (m *PartitionLag) GetTopic() string {
+	if m != nil {
+		return m.Topic
+	}
+	return ""
+}
+
+func (m *PartitionLag) GetPartition() int32 {
+	if m != nil {
+		return m.Partition
+	}
+	return 0
+}
+
+func (m *PartitionLag) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *PartitionLag) GetCurrentLag() int64 {
+	if m != nil {
+		return m.CurrentLag
+	}
+	return 0
+}
+
+func (m *PartitionLag) GetStartOffset() int64 {
+	if m != nil {
+		return m.StartOffset
+	}
+	return 0
+}
+
+func (m *PartitionLag) GetEndOffset() int64 {
+	if m != nil {
+		return m.EndOffset
+	}
+	return 0
+}
+
+func (m *PartitionLag) GetComplete() float64 {
+	if m != nil {
+		return m.Complete
+	}
+	return 0
+}
+
+func (m *PartitionLag) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+func (m *PartitionLag) GetClientId() string {
+	if m != nil {
+		return m.ClientId
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*ListGroupsRequest)(nil), "burrow_exporter.v1.ListGroupsRequest")
+	proto.RegisterType((*ListGroupsResponse)(nil), "burrow_exporter.v1.ListGroupsResponse")
+	proto.RegisterType((*GroupSummary)(nil), "burrow_exporter.v1.GroupSummary")
+	proto.RegisterType((*GetGroupLagRequest)(nil), "burrow_exporter.v1.GetGroupLagRequest")
+	proto.RegisterType((*WatchGroupRequest)(nil), "burrow_exporter.v1.WatchGroupRequest")
+	proto.RegisterType((*GroupLag)(nil), "burrow_exporter.v1.GroupLag")
+	proto.RegisterType((*PartitionLag)(nil), "burrow_exporter.v1.PartitionLag")
+}
+
+func init() { proto.RegisterFile("lag.proto", fileDescriptor_932644ad334c12e5) }
+
+var fileDescriptor_932644ad334c12e5 = []byte{
+	// 557 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x94, 0xcf, 0x8e, 0xd3, 0x30,
+	0x10, 0xc6, 0x95, 0x96, 0xa6, 0xcd, 0xb4, 0x97, 0xb5, 0x56, 0x28, 0x2a, 0x8b, 0x36, 0x54, 0x02,
+	0xca, 0x25, 0x81, 0x72, 0x81, 0x1b, 0xff, 0xa4, 0x15, 0x52, 0x05, 0x28, 0x45, 0x42, 0x82, 0x43,
+	0xe5, 0xa6, 0x6e, 0x1a, 0x29, 0x89, 0x8d, 0x3d, 0x61, 0xcb, 0x3b, 0xec, 0xb3, 0xf0, 0x12, 0xbc,
+	0x18, 0x8a, 0x93, 0xb4, 0xa1, 0x69, 0x81, 0x45, 0x7b, 0x9c, 0xcf, 0xf3, 0x4d, 0x26, 0xbf, 0xf1,
+	0x18, 0xac, 0x98, 0x86, 0xae, 0x90, 0x1c, 0x39, 0x21, 0x8b, 0x4c, 0x4a, 0x7e, 0x39, 0x67, 0x1b,
+	0xc1, 0x25, 0x32, 0xe9, 0x7e, 0x7b, 0x32, 0x3c, 0x0f, 0x39, 0x0f, 0x63, 0xe6, 0xe9, 0x8c, 0x45,
+	0xb6, 0xf2, 0x30, 0x4a, 0x98, 0x42, 0x9a, 0x88, 0xc2, 0x34, 0xf2, 0xe0, 0x64, 0x1a, 0x29, 0xbc,
+	0x90, 0x3c, 0x13, 0xca, 0x67, 0x5f, 0x33, 0xa6, 0x90, 0x0c, 0xa1, 0x17, 0xc4, 0x99, 0x42, 0x26,
+	0x95, 0x6d, 0x38, 0xed, 0xb1, 0xe5, 0x6f, 0xe3, 0xd1, 0x3b, 0x20, 0x75, 0x83, 0x12, 0x3c, 0x55,
+	0x8c, 0x3c, 0x03, 0x33, 0xd4, 0x8a, 0xce, 0xef, 0x4f, 0x1c, 0xb7, 0xd9, 0x8c, 0xab, 0x3d, 0xb3,
+	0x2c, 0x49, 0xa8, 0xfc, 0xee, 0x97, 0xf9, 0xa3, 0x1f, 0x06, 0x0c, 0xea, 0x07, 0xc4, 0x86, 0x6e,
+	0xf9, 0x31, 0xdb, 0x70, 0x8c, 0xb1, 0xe5, 0x57, 0x21, 0x39, 0x85, 0x8e, 0x36, 0xd9, 0x2d, 0xad,
+	0x17, 0x01, 0xb9, 0x0d, 0xa6, 0x42, 0x8a, 0x99, 0xb2, 0xdb, 0x5a, 0x2e, 0x23, 0x72, 0x07, 0x2c,
+	0xe4, 0x48, 0xe3, 0x79, 0x4c, 0x43, 0xfb, 0x96, 0x63, 0x8c, 0xdb, 0x7e, 0x4f, 0x0b, 0x53, 0x1a,
+	0x92, 0xe7, 0x00, 0x2a, 0x90, 0x54, 0xb0, 0xe5, 0x9c, 0xa2, 0xdd, 0x71, 0x8c, 0x71, 0x7f, 0x32,
+	0x74, 0x0b, 0x58, 0x6e, 0x05, 0xcb, 0xfd, 0x58, 0xc1, 0xf2, 0xad, 0x32, 0xfb, 0x25, 0x8e, 0xde,
+	0x00, 0xb9, 0x60, 0xc5, 0xff, 0x4f, 0x69, 0x58, 0x21, 0xbb, 0x66, 0xd7, 0xa3, 0xd7, 0x70, 0xf2,
+	0x89, 0x62, 0xb0, 0xd6, 0x75, 0xfe, 0xb7, 0xc8, 0xcf, 0x16, 0xf4, 0xaa, 0x46, 0x6e, 0x8c, 0x5b,
+	0x3e, 0x7c, 0x9e, 0x88, 0x98, 0x21, 0xd3, 0xd8, 0x0c, 0x7f, 0x1b, 0xff, 0xce, 0xb4, 0xd3, 0x60,
+	0xda, 0x4d, 0xe8, 0x46, 0x1f, 0x99, 0x8e, 0x71, 0xec, 0x12, 0x7c, 0xa0, 0x12, 0x23, 0x8c, 0x78,
+	0x9a, 0xc3, 0x33, 0x13, 0xba, 0xc9, 0xad, 0x2f, 0x00, 0x44, 0xa5, 0x2b, 0xbb, 0xeb, 0xb4, 0xff,
+	0xc9, 0x5d, 0xf3, 0xec, 0x0d, 0xb4, 0x77, 0x9d, 0x81, 0x5e, 0xb5, 0x60, 0x50, 0xaf, 0x9b, 0xf3,
+	0x42, 0x2e, 0xa2, 0xa0, 0xe4, 0x58, 0x04, 0xe4, 0x0c, 0xac, 0xed, 0xf7, 0x34, 0xc9, 0x8e, 0xbf,
+	0x13, 0x8e, 0xd2, 0x3c, 0x87, 0x7e, 0x90, 0x49, 0xc9, 0x52, 0xac, 0xdd, 0x43, 0x28, 0xa5, 0xfc,
+	0x63, 0xf7, 0x60, 0xa0, 0x90, 0x4a, 0x9c, 0xf3, 0xd5, 0x4a, 0x31, 0x2c, 0xa9, 0xf6, 0xb5, 0xf6,
+	0x5e, 0x4b, 0xe4, 0x2e, 0x00, 0x4b, 0x97, 0x55, 0x82, 0xa9, 0x13, 0x2c, 0x96, 0x2e, 0xcb, 0xe3,
+	0xfa, 0xc0, 0xba, 0x7b, 0x03, 0x3b, 0x85, 0x0e, 0xbf, 0x4c, 0x99, 0xd4, 0x44, 0x2c, 0xbf, 0x08,
+	0xf2, 0x31, 0x06, 0x71, 0x94, 0xf7, 0x14, 0x2d, 0x6d, 0x4b, 0x9f, 0xf4, 0x0a, 0xe1, 0xed, 0x72,
+	0x72, 0xd5, 0x82, 0x76, 0xde, 0xd8, 0x17, 0x80, 0xdd, 0xa2, 0x93, 0xfb, 0x87, 0xa6, 0xd1, 0x78,
+	0x39, 0x86, 0x0f, 0xfe, 0x96, 0x56, 0xbe, 0x17, 0x33, 0xe8, 0xd7, 0x96, 0x88, 0x1c, 0xb4, 0x35,
+	0xb7, 0x6c, 0x78, 0x76, 0xf4, 0x59, 0xc9, 0xab, 0xcc, 0x00, 0x76, 0x3b, 0x75, 0xb8, 0xe3, 0xc6,
+	0xce, 0xfd, 0xb9, 0xe4, 0x63, 0xe3, 0xd5, 0xa3, 0xcf, 0x0f, 0xc3, 0x08, 0xd7, 0xd9, 0xc2, 0x0d,
+	0x78, 0xe2, 0xa9, 0x35, 0x4d, 0xa2, 0xd8, 0xdb, 0xb3, 0x78, 0x31, 0x0d, 0xc5, 0x62, 0x61, 0xea,
+	0x7b, 0xf6, 0xf4, 0xd7, 0x00, 0x42, 0xac, 0xae, 0x2b, 0x94, 0x05, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// LagClient is the client API for Lag service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type LagClient interface {
+	// ListGroups returns the status and total lag of the consumer groups.
+	ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error)
+	// GetGroupLag returns the lag of a consumer group, by partition.
+	GetGroupLag(ctx context.Context, in *GetGroupLagRequest, opts ...grpc.CallOption) (*GroupLag, error)
+	// WatchGroup streams the lag of a consumer group, as of the latest scrape
+	// and then on every refresh of its cluster.
+	WatchGroup(ctx context.Context, in *WatchGroupRequest, opts ...grpc.CallOption) (Lag_WatchGroupClient, error)
+}
+
+type lagClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLagClient(cc *grpc.ClientConn) LagClient {
+	return &lagClient{cc}
+}
+
+func (c *lagClient) ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error) {
+	out := new(ListGroupsResponse)
+	err := c.cc.Invoke(ctx, "/burrow_exporter.v1.Lag/ListGroups", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lagClient) GetGroupLag(ctx context.Context, in *GetGroupLagRequest, opts ...grpc.CallOption) (*GroupLag, error) {
+	out := new(GroupLag)
+	err := c.cc.Invoke(ctx, "/burrow_exporter.v1.Lag/GetGroupLag", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lagClient) WatchGroup(ctx context.Context, in *WatchGroupRequest, opts ...grpc.CallOption) (Lag_WatchGroupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Lag_serviceDesc.Streams[0], "/burrow_exporter.v1.Lag/WatchGroup", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &lagWatchGroupClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Lag_WatchGroupClient interface {
+	Recv() (*GroupLag, error)
+	grpc.ClientStream
+}
+
+type lagWatchGroupClient struct {
+	grpc.ClientStream
+}
+
+func (x *lagWatchGroupClient) Recv() (*GroupLag, error) {
+	m := new(GroupLag)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LagServer is the server API for Lag service.
+type LagServer interface {
+	// ListGroups returns the status and total lag of the consumer groups.
+	ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error)
+	// GetGroupLag returns the lag of a consumer group, by partition.
+	GetGroupLag(context.Context, *GetGroupLagRequest) (*GroupLag, error)
+	// WatchGroup streams the lag of a consumer group, as of the latest scrape
+	// and then on every refresh of its cluster.
+	WatchGroup(*WatchGroupRequest, Lag_WatchGroupServer) error
+}
+
+// UnimplementedLagServer can be embedded to have forward compatible implementations.
+type UnimplementedLagServer struct {
+}
+
+func (*UnimplementedLagServer) ListGroups(ctx context.Context, req *ListGroupsRequest) (*ListGroupsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGroups not implemented")
+}
+func (*UnimplementedLagServer) GetGroupLag(ctx context.Context, req *GetGroupLagRequest) (*GroupLag, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGroupLag not implemented")
+}
+func (*UnimplementedLagServer) WatchGroup(req *WatchGroupRequest, srv Lag_WatchGroupServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchGroup not implemented")
+}
+
+func RegisterLagServer(s *grpc.Server, srv LagServer) {
+	s.RegisterService(&_Lag_serviceDesc, srv)
+}
+
+func _Lag_ListGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LagServer).ListGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/burrow_exporter.v1.Lag/ListGroups",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LagServer).ListGroups(ctx, req.(*ListGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lag_GetGroupLag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGroupLagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LagServer).GetGroupLag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/burrow_exporter.v1.Lag/GetGroupLag",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LagServer).GetGroupLag(ctx, req.(*GetGroupLagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Lag_WatchGroup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchGroupRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LagServer).WatchGroup(m, &lagWatchGroupServer{stream})
+}
+
+type Lag_WatchGroupServer interface {
+	Send(*GroupLag) error
+	grpc.ServerStream
+}
+
+type lagWatchGroupServer struct {
+	grpc.ServerStream
+}
+
+func (x *lagWatchGroupServer) Send(m *GroupLag) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Lag_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "burrow_exporter.v1.Lag",
+	HandlerType: (*LagServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListGroups",
+			Handler:    _Lag_ListGroups_Handler,
+		},
+		{
+			MethodName: "GetGroupLag",
+			Handler:    _Lag_GetGroupLag_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchGroup",
+			Handler:       _Lag_WatchGroup_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lag.proto",
+}
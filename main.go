@@ -1,52 +1,662 @@
 package main
 
 import (
+	"context"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
+	"github.com/prometheus/client_golang/prometheus/graphite"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/version"
 	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+	"github.com/shamil/burrow_exporter/internal/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 func init() {
-	prometheus.MustRegister(version.NewCollector("burrow_exporter"))
+	prometheus.MustRegister(versioncollector.NewCollector("burrow_exporter"))
 }
 
 func main() {
 	var (
-		listenAddress            = kingpin.Flag("web.listen-address", "Address to listen on for web interface and telemetry.").Short('l').Default(":8237").String()
+		_                        = kingpin.Command("serve", "Serve burrow's metrics.").Default()
+		benchCommand             = kingpin.Command("bench", "Measure the refreshes of synthetic burrow data, with the collector configured by the flags. The allocations include those of the in-process synthetic burrow.")
+		benchClusters            = benchCommand.Flag("clusters", "Number of synthetic clusters.").Default("1").Int()
+		benchGroups              = benchCommand.Flag("groups", "Number of consumer groups per cluster.").Default("100").Int()
+		benchPartitions          = benchCommand.Flag("partitions", "Number of partitions per consumer group.").Default("10").Int()
+		benchRefreshes           = benchCommand.Flag("refreshes", "Number of refreshes to measure.").Default("5").Int()
+		listenAddresses          = kingpin.Flag("web.listen-address", "Address to listen on for web interface and telemetry, repeat for each, e.g. to bind both IPv4 and IPv6. A web config file replacing --web.config.file for the address only can be given as <address>=<file>.").Short('l').Default(":8237").Strings()
+		grpcListenAddress        = kingpin.Flag("web.grpc-listen-address", "Address to serve the lag of the consumer groups over gRPC on, see lagpb/lag.proto. It uses the TLS settings and basic auth users of the web config file, the credentials being given in the authorization metadata. Disabled when empty.").String()
+		probeTargets             = kingpin.Flag("web.probe.target-regex", "Regex of the burrow addresses that may be scraped on demand on /probe?target=<address>, /probe is disabled when empty.").String()
+		systemdSocket            = kingpin.Flag("web.systemd-socket", "Serve on the sockets passed by systemd's socket activation instead of --web.listen-address, with --web.config.file (Linux only).").Bool()
+		accessLogEnabled         = kingpin.Flag("web.access-log", "Log every request to the exporter, with its method, path, status, duration and client.").Bool()
+		shutdownTimeout          = kingpin.Flag("web.shutdown-timeout", "Time to wait for the in-flight requests to be served when shutting down.").Default("30s").Duration()
+		webConfigFile            = kingpin.Flag("web.config.file", "Path to a web config file, in the exporter toolkit format, enabling TLS or basic auth.").String()
+		healthzMaxScrape         = kingpin.Flag("web.healthz.max-scrape-duration", "Duration of a scrape after which /healthz reports the exporter as stuck.").Default("5m").Duration()
+		readyRequireBurrow       = kingpin.Flag("web.ready.require-burrow", "Only report the exporter ready on /ready while the last scrape of burrow succeeded, rather than once any did.").Bool()
+		enableLifecycle          = kingpin.Flag("web.enable-lifecycle", "Enable refreshing burrow's data and reloading the configuration over HTTP, with POST requests to /-/refresh and /-/reload.").Bool()
 		metricsPath              = kingpin.Flag("web.telemetry-path", "Path under which to expose metrics.").Default("/metrics").String()
-		burrowAddress            = kingpin.Flag("burrow.address", "Burrow API address.").Default("http://localhost:8000").String()
+		burrowAddresses          = kingpin.Flag("burrow.address", "Burrow API address, repeat to fail over to the next address when the current one is unhealthy.").Default("http://localhost:8000").Strings()
 		burrowAPIVersion         = kingpin.Flag("burrow.api-version", "Burrow API version to leverage.").Default("3").Int()
-		collectorDisabledMetrics = kingpin.Flag("collector.disabled-metrics", "Comma separated list of metrics to disable (one of: consumer-status, partition-current-offset, partition-lag, partition-max-offset, partition-status, topic-partition-offset, total-lag).").Default("").String()
+		burrowTimeoutList        = kingpin.Flag("burrow.timeout.list", "Timeout of burrow requests listing clusters, consumer groups and topics.").Default("30s").Duration()
+		burrowTimeoutStatus      = kingpin.Flag("burrow.timeout.status", "Timeout of burrow requests fetching a consumer group status.").Default("30s").Duration()
+		burrowTimeoutHealth      = kingpin.Flag("burrow.timeout.health-check", "Timeout of the burrow health check.").Default("30s").Duration()
+		burrowMaxConcurrent      = kingpin.Flag("burrow.max-concurrent-requests", "Maximum number of concurrent requests to burrow, the others wait for a slot, 0 means no limit.").Default("0").Int()
+		burrowRetries            = kingpin.Flag("burrow.retries", "Number of retries of a failed burrow request, after failing over all the burrow addresses.").Default("0").Int()
+		burrowRetryBackoff       = kingpin.Flag("burrow.retry-backoff", "Delay before the first retry, doubled on every following one up to the maximum.").Default("1s").Duration()
+		burrowRetryMaxBackoff    = kingpin.Flag("burrow.retry-max-backoff", "Maximum delay between the retries, 0 means no limit.").Default("30s").Duration()
+		burrowRetryBudgetRatio   = kingpin.Flag("burrow.retry-budget.ratio", "Maximum ratio of retries to burrow requests within the budget interval, 0 disables the budget.").Default("0.1").Float64()
+		burrowRetryBudgetPeriod  = kingpin.Flag("burrow.retry-budget.interval", "Interval the retry budget is computed over.").Default("1m").Duration()
+		burrowHTTP2              = kingpin.Flag("burrow.http2", "Attempt HTTP/2 when connecting to burrow over TLS.").Default("true").Bool()
+		burrowTLSHandshake       = kingpin.Flag("burrow.tls-handshake-timeout", "Timeout of the TLS handshake with burrow.").Default("10s").Duration()
+		burrowExpectContinue     = kingpin.Flag("burrow.expect-continue-timeout", "Time to wait for burrow's first response headers after sending the request headers, when the request has an \"Expect: 100-continue\" header.").Default("1s").Duration()
+		burrowMaxIdleConns       = kingpin.Flag("burrow.max-idle-conns", "Maximum number of idle (keep-alive) connections to burrow.").Default("100").Int()
+		burrowMaxIdlePerHost     = kingpin.Flag("burrow.max-idle-conns-per-host", "Maximum number of idle (keep-alive) connections per burrow host.").Default("2").Int()
+		burrowMaxConnsPerHost    = kingpin.Flag("burrow.max-conns-per-host", "Maximum number of connections per burrow host, 0 means no limit.").Default("0").Int()
+		burrowIdleConnTimeout    = kingpin.Flag("burrow.idle-conn-timeout", "Time an idle (keep-alive) connection to burrow remains open.").Default("90s").Duration()
+		cacheTTLClusters         = kingpin.Flag("burrow.cache-ttl.clusters", "Time to cache the burrow cluster list and details for, 0 disables caching.").Default("0s").Duration()
+		cacheTTLConsumers        = kingpin.Flag("burrow.cache-ttl.consumers", "Time to cache the consumer group lists for, 0 disables caching.").Default("0s").Duration()
+		cacheTTLTopics           = kingpin.Flag("burrow.cache-ttl.topics", "Time to cache the topic lists for, 0 disables caching.").Default("0s").Duration()
+		cacheTTLTopicOffsets     = kingpin.Flag("burrow.cache-ttl.topic-offsets", "Time to cache the topic partition offsets for, 0 disables caching.").Default("0s").Duration()
+		cacheTTLStatus           = kingpin.Flag("burrow.cache-ttl.status", "Time to cache the consumer group status for, 0 disables caching.").Default("0s").Duration()
+		conditionalRequests      = kingpin.Flag("burrow.conditional-requests", "Revalidate burrow responses having an ETag or Last-Modified validator with conditional requests, reusing the previous body when not modified.").Default("false").Bool()
+		oauth2TokenURL           = kingpin.Flag("burrow.oauth2.token-url", "OAuth2 token URL, enables the client-credentials grant for burrow requests.").Default("").String()
+		oauth2ClientID           = kingpin.Flag("burrow.oauth2.client-id", "OAuth2 client id.").Default("").String()
+		oauth2ClientSecret       = kingpin.Flag("burrow.oauth2.client-secret", "OAuth2 client secret, can also be set with the BURROW_OAUTH2_CLIENT_SECRET environment variable.").Envar("BURROW_OAUTH2_CLIENT_SECRET").Default("").String()
+		oauth2Scopes             = kingpin.Flag("burrow.oauth2.scope", "OAuth2 scope to request, can be repeated.").Strings()
+		kerberosEnabled          = kingpin.Flag("burrow.kerberos", "Authenticate to burrow using Kerberos/SPNEGO.").Default("false").Bool()
+		kerberosConfig           = kingpin.Flag("burrow.kerberos.config", "Path to the kerberos configuration file.").Default("/etc/krb5.conf").String()
+		kerberosKeytab           = kingpin.Flag("burrow.kerberos.keytab", "Path to the keytab used to log in, the credential cache is used when empty.").Default("").String()
+		kerberosPrincipal        = kingpin.Flag("burrow.kerberos.principal", "Principal (user@REALM) to log in with the keytab.").Default("").String()
+		kerberosCCache           = kingpin.Flag("burrow.kerberos.ccache", "Path to the credential cache, defaults to $KRB5CCNAME or /tmp/krb5cc_<uid>.").Default("").String()
+		kerberosSPN              = kingpin.Flag("burrow.kerberos.spn", "Service principal name of burrow, defaults to HTTP/<burrow host>.").Default("").String()
+		recordDir                = kingpin.Flag("burrow.record-dir", "Directory to record the raw burrow responses to, for replaying them later.").Default("").String()
+		replayDir                = kingpin.Flag("burrow.replay-dir", "Directory to replay previously recorded burrow responses from, instead of querying burrow.").Default("").String()
+		tracingEndpoint          = kingpin.Flag("tracing.otlp-endpoint", "URL of an OTLP/HTTP collector to send the traces of the requests to burrow to, e.g. http://localhost:4318/v1/traces. The trace context is propagated to burrow. Disabled when empty.").String()
+		tracingSamplingRatio     = kingpin.Flag("tracing.sampling-ratio", "Ratio of the calls to burrow to trace, from 0 to 1.").Default("1").Float64()
+		collectorDisabledMetrics = kingpin.Flag("collector.disabled-metrics", "Comma separated list of metrics to disable (one of: catch-up, cluster-info, cluster-lag, commit-age, complete, consumer-groups, consumer-status, group-status, lag-velocity, max-lag, max-lag-partition, max-time-lag, partition-commit-age, partition-current-offset, partition-lag, partition-max-offset, partition-owner, partition-status, partition-status-count, partition-time-lag, partition-timestamp, partition-window, stalled-partitions, topic-lag, topic-partition-offset, topic-partitions, topics, total-lag).").Default("").String()
+		partitionMetrics         = kingpin.Flag("collector.partition-metrics", "Export the per partition metrics, disable to only export group and cluster level metrics.").Default("true").Bool()
+		aggregateOnly            = kingpin.Flag("collector.aggregate-only", "Only export the per group totals (total lag, max lag and status) and per topic lag sums, skipping all partition detail.").Default("false").Bool()
+		clusterLabels            = kingpin.Flag("collector.cluster-label", "Extra label added to all the metrics of a cluster, e.g. an alias or environment, as <cluster>:<label>=<value>, repeat for more labels or clusters.").Strings()
+		groupRewrites            = kingpin.Flag("collector.group-rewrite", "Rule rewriting consumer group names, e.g. to strip instance IDs, as <regex>=<replacement> where the replacement may refer to capture groups ($1), repeat for more rules, the first matching one applies. The groups rewritten to the same name are merged, summing up their lag.").Strings()
+		rawGroupLabel            = kingpin.Flag("collector.group-raw-label", "Add the consumer group name before rewriting as the raw_group label.").Default("false").Bool()
+		topicRewrites            = kingpin.Flag("collector.topic-rewrite", "Rule rewriting topic names, e.g. to strip prefixes, as <regex>=<replacement>, repeat for more rules, the first matching one applies.").Strings()
+		topicTenants             = kingpin.Flag("collector.topic-tenant", "Rule mapping topic names to the tenant label of the topic metrics, as <regex>=<replacement>, repeat for more rules, the first matching one applies.").Strings()
+		excludeInternal          = kingpin.Flag("collector.exclude-internal-topics", "Skip the metrics of kafka's internal topics, starting with a double underscore, e.g. __consumer_offsets.").Default("true").Bool()
+		topicInclude             = kingpin.Flag("collector.topic-include", "Regex of the topics to export the topic and partition metrics of, all topics when empty.").Default("").String()
+		topicExclude             = kingpin.Flag("collector.topic-exclude", "Regex of the topics to skip the topic and partition metrics of, e.g. high partition count firehose topics.").Default("").String()
+		filterRulesFile          = kingpin.Flag("collector.filter-rules-file", "YAML file of cluster, consumer group and topic include/exclude regexes, reloaded whenever it changes.").Default("").String()
+		topGroups                = kingpin.Flag("collector.top-groups", "Only export the per partition metrics of this many consumer groups with the highest total lag per cluster, the others only get group level metrics, 0 exports all.").Default("0").Int()
+		maxSeries                = kingpin.Flag("collector.max-series", "Maximum number of series exported from burrow data, the partition metrics are dropped first, then the topic ones, when exceeded, 0 disables the limit.").Default("0").Int()
+		minPartitionLag          = kingpin.Flag("collector.min-partition-lag", "Only export the per partition metrics of partitions with at least this lag, 0 exports all.").Default("0").Int64()
+		metricTimestamps         = kingpin.Flag("collector.metric-timestamps", "Export the consumer group samples with the time of the latest offset commit burrow evaluated, beware Prometheus drops samples older than about an hour.").Default("false").Bool()
+		skipIncomplete           = kingpin.Flag("collector.skip-incomplete", "Leave out the lag metrics of consumer groups whose burrow evaluation window isn't full yet, to avoid alerting on partial evaluations.").Default("false").Bool()
+		windowOffsets            = kingpin.Flag("collector.window-offsets", "Export the number of offsets in burrow's evaluation window of each partition, which takes another request per consumer group.").Default("false").Bool()
+		refreshInterval          = kingpin.Flag("collector.refresh-interval", "Minimum time between two scrapes of a cluster, the metrics of the previous scrape are served in between, 0 scrapes on every collection.").Default("0s").Duration()
+		clusterRefresh           = kingpin.Flag("collector.cluster-refresh-interval", "Refresh interval of a single cluster overriding --collector.refresh-interval, as <cluster>=<duration>, repeat for more clusters.").Strings()
+		incrementalRefresh       = kingpin.Flag("collector.incremental-refresh", "Only fetch the consumer groups that committed since their previous fetch again, fetching all of them on every Nth scrape of a cluster only, 0 disables it.").Default("0").Int()
+		maxIdleGroupAge          = kingpin.Flag("collector.incremental-refresh.max-idle-age", "Fetch the idle consumer groups again once their status is this old, even between the full refreshes, 0 means no limit.").Default("5m").Duration()
+		adaptiveRefresh          = kingpin.Flag("collector.adaptive-refresh", "Stretch the refresh interval of each cluster to at least this many times its scrape duration, so clusters with many groups don't keep burrow busy all the time, 0 disables it.").Default("0").Float64()
+		scrapeTimeoutOffset      = kingpin.Flag("collector.scrape-timeout-offset", "Offset to subtract from prometheus' scrape timeout, given in the X-Prometheus-Scrape-Timeout-Seconds header, to bound the requests to burrow by.").Default("500ms").Duration()
+		leaseFile                = kingpin.Flag("collector.leader-lease-file", "Lease file shared by the exporter replicas to elect the one scraping burrow, the others stand by, disabled when empty.").String()
+		leaseDuration            = kingpin.Flag("collector.leader-lease-duration", "Time after which the lease of a leader that didn't renew it is taken over, must exceed the scrape interval.").Default("1m").Duration()
+		leaseIdentity            = kingpin.Flag("collector.leader-identity", "Identity of this replica in the leader lease, defaults to the hostname.").String()
+		shardIndex               = kingpin.Flag("collector.shard-index", "Index of this exporter replica, from 0 to --collector.shard-count - 1.").Default("0").Int()
+		shardCount               = kingpin.Flag("collector.shard-count", "Number of exporter replicas splitting the consumer groups and topics between them, each scraping its shard only.").Default("1").Int()
+		memoryBudget             = kingpin.Flag("collector.memory-budget", "Memory budget, e.g. 512MB, the partition metrics are dropped while the memory used approaches it, 0 disables it.").Default("0").Bytes()
+		snapshotDir              = kingpin.Flag("collector.snapshot-dir", "Directory to save the metrics and consumer group lag of each cluster's scrapes to, restoring them on startup so restarts serve them right away while burrow is scraped in the background, disabled when empty.").String()
+		staleGrace               = kingpin.Flag("collector.stale-grace", "Keep serving the previous metrics of a cluster for up to this long after its last successful scrape when scraping it fails, 0 disables it.").Default("0s").Duration()
+		scrapeJitter             = kingpin.Flag("collector.scrape-jitter", "Delay the scrape of each cluster by a random duration up to this long, and skew their refresh intervals by as much, to spread the requests to burrow. It adds to the scrape duration.").Default("0s").Duration()
+		pushGatewayURL           = kingpin.Flag("push.gateway-url", "URL of a pushgateway to push the metrics to, for when prometheus can't reach the exporter, each cluster's as the group of its cluster label once refreshed. Disabled when empty.").String()
+		pushInterval             = kingpin.Flag("push.interval", "Interval of the pushes to the pushgateway, scraping burrow when due.").Default("1m").Duration()
+		pushJob                  = kingpin.Flag("push.job", "Job label of the metrics pushed to the pushgateway.").Default("burrow_exporter").String()
+		graphiteAddress          = kingpin.Flag("graphite.address", "Address (host:port) of a graphite server to send the metrics to in its plaintext protocol, disabled when empty.").String()
+		graphitePrefix           = kingpin.Flag("graphite.prefix", "Prefix of the metric paths sent to graphite, e.g. kafka.burrow.").String()
+		graphiteInterval         = kingpin.Flag("graphite.interval", "Interval of the flushes to graphite, scraping burrow when due.").Default("1m").Duration()
+		influxURL                = kingpin.Flag("influxdb.url", "URL of an InfluxDB to write the metrics to in its line protocol, disabled when empty.").String()
+		influxAPIVersion         = kingpin.Flag("influxdb.api-version", "Version of InfluxDB's write API, 1 (database, retention policy and basic auth) or 2 (org, bucket and token).").Default("1").Enum("1", "2")
+		influxDatabase           = kingpin.Flag("influxdb.database", "Database to write the metrics to, with the v1 API.").String()
+		influxRetention          = kingpin.Flag("influxdb.retention-policy", "Retention policy of the metrics, with the v1 API, the database's default when empty.").String()
+		influxUsername           = kingpin.Flag("influxdb.username", "Username of the v1 API.").String()
+		influxPassword           = kingpin.Flag("influxdb.password", "Password of the v1 API, can also be set with the INFLUXDB_PASSWORD environment variable.").Envar("INFLUXDB_PASSWORD").String()
+		influxOrg                = kingpin.Flag("influxdb.org", "Organization to write the metrics to, with the v2 API.").String()
+		influxBucket             = kingpin.Flag("influxdb.bucket", "Bucket to write the metrics to, with the v2 API.").String()
+		influxToken              = kingpin.Flag("influxdb.token", "Token of the v2 API, can also be set with the INFLUXDB_TOKEN environment variable.").Envar("INFLUXDB_TOKEN").String()
+		influxInterval           = kingpin.Flag("influxdb.interval", "Interval of the writes to InfluxDB, scraping burrow when due.").Default("1m").Duration()
+		runtimeMetrics           = kingpin.Flag("collector.runtime-metrics", "Export the Go runtime and process metrics of the exporter itself.").Default("true").Bool()
+		lagBuckets               = kingpin.Flag("collector.lag-histogram-bucket", "Upper bound of a bucket of the per group partition lag histogram, repeat for each bucket, the histogram is only exported when set.").Float64List()
+		lagNativeFactor          = kingpin.Flag("collector.lag-histogram-native-factor", "Export the per group partition lag histogram as a native histogram, whose bucket bounds grow by up to this factor, e.g. 1.1, along with the classic buckets when set. Native histograms are only served in the protobuf format, 0 disables it.").Default("0").Float64()
 	)
 
 	log.AddFlags(kingpin.CommandLine)
 	kingpin.Version(version.Print("burrow_exporter"))
 	kingpin.HelpFlag.Short('h')
-	kingpin.Parse()
+	command := kingpin.Parse()
+
+	if *burrowRetryBudgetRatio > 0 && *burrowRetryBudgetPeriod <= 0 {
+		log.Fatalf("Invalid retry budget interval %v, it must be above 0", *burrowRetryBudgetPeriod)
+	}
+
+	clientOpts := []exporter.ClientOption{
+		exporter.WithTransportConfig(exporter.TransportConfig{
+			DisableHTTP2:          !*burrowHTTP2,
+			TLSHandshakeTimeout:   *burrowTLSHandshake,
+			ExpectContinueTimeout: *burrowExpectContinue,
+			MaxIdleConns:          *burrowMaxIdleConns,
+			MaxIdleConnsPerHost:   *burrowMaxIdlePerHost,
+			MaxConnsPerHost:       *burrowMaxConnsPerHost,
+			IdleConnTimeout:       *burrowIdleConnTimeout,
+		}),
+		exporter.WithTimeouts(exporter.Timeouts{
+			List:        *burrowTimeoutList,
+			Status:      *burrowTimeoutStatus,
+			HealthCheck: *burrowTimeoutHealth,
+		}),
+		exporter.WithCacheTTLs(exporter.CacheTTLs{
+			Clusters:     *cacheTTLClusters,
+			Consumers:    *cacheTTLConsumers,
+			Topics:       *cacheTTLTopics,
+			TopicOffsets: *cacheTTLTopicOffsets,
+			Status:       *cacheTTLStatus,
+		}),
+		exporter.WithRetryPolicy(exporter.RetryPolicy{
+			MaxRetries:     *burrowRetries,
+			Backoff:        *burrowRetryBackoff,
+			MaxBackoff:     *burrowRetryMaxBackoff,
+			BudgetRatio:    *burrowRetryBudgetRatio,
+			BudgetInterval: *burrowRetryBudgetPeriod,
+		}),
+	}
+
+	if *burrowMaxConcurrent > 0 {
+		clientOpts = append(clientOpts, exporter.WithMaxConcurrentRequests(*burrowMaxConcurrent))
+	}
+
+	if *conditionalRequests {
+		clientOpts = append(clientOpts, exporter.WithConditionalRequests())
+	}
+
+	if *oauth2TokenURL != "" {
+		clientOpts = append(clientOpts, exporter.WithOAuth2(exporter.OAuth2Config{
+			TokenURL:     *oauth2TokenURL,
+			ClientID:     *oauth2ClientID,
+			ClientSecret: *oauth2ClientSecret,
+			Scopes:       *oauth2Scopes,
+		}))
+	}
+
+	if *kerberosEnabled {
+		opt, err := exporter.WithKerberos(exporter.KerberosConfig{
+			ConfigFile: *kerberosConfig,
+			Keytab:     *kerberosKeytab,
+			Principal:  *kerberosPrincipal,
+			CCache:     *kerberosCCache,
+			SPN:        *kerberosSPN,
+		})
+		if err != nil {
+			log.Fatalf("Failed setting up kerberos authentication: %v", err)
+		}
+
+		clientOpts = append(clientOpts, opt)
+	}
+
+	if *recordDir != "" && *replayDir != "" {
+		log.Fatal("Only one of --burrow.record-dir and --burrow.replay-dir can be set")
+	}
+
+	if *recordDir != "" {
+		clientOpts = append(clientOpts, exporter.WithRecording(*recordDir))
+	}
+
+	if *replayDir != "" {
+		clientOpts = append(clientOpts, exporter.WithReplay(*replayDir))
+	}
+
+	var tracerProvider *sdktrace.TracerProvider
+	if *tracingEndpoint != "" {
+		var err error
+		if tracerProvider, err = newTracerProvider(*tracingEndpoint, *tracingSamplingRatio); err != nil {
+			log.Fatalf("Failed setting up tracing: %v", err)
+		}
+
+		clientOpts = append(clientOpts, exporter.WithTracerProvider(tracerProvider))
+	}
+
+	// The bench scrapes the synthetic burrow only.
+	var client *exporter.BurrowClient
+	if command == benchCommand.FullCommand() {
+		server := burrowtest.NewServer(burrowtest.Synthetic(*benchClusters, *benchGroups, *benchPartitions))
+		defer server.Close()
+
+		client = server.Client(*burrowAPIVersion, clientOpts...)
+		defer client.Close()
+	} else {
+		client = exporter.NewBurrowClient(*burrowAddresses, *burrowAPIVersion, clientOpts...)
+	}
+
+	var collectorOpts []exporter.CollectorOption
+
+	if !*partitionMetrics {
+		collectorOpts = append(collectorOpts, exporter.WithoutPartitionMetrics())
+	}
+
+	if *aggregateOnly {
+		collectorOpts = append(collectorOpts, exporter.WithAggregateOnly())
+	}
+
+	if *topGroups > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithTopGroups(*topGroups))
+	}
+
+	if *windowOffsets {
+		collectorOpts = append(collectorOpts, exporter.WithWindowOffsets())
+	}
+
+	if *skipIncomplete {
+		collectorOpts = append(collectorOpts, exporter.WithoutIncompleteLag())
+	}
+
+	if *minPartitionLag > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithMinPartitionLag(*minPartitionLag))
+	}
+
+	if *metricTimestamps {
+		collectorOpts = append(collectorOpts, exporter.WithMetricTimestamps())
+	}
+
+	if *maxSeries > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithMaxSeries(*maxSeries))
+	}
+
+	if len(*lagBuckets) > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithLagHistogram(*lagBuckets))
+	}
+
+	if *lagNativeFactor > 0 {
+		if *lagNativeFactor <= 1 {
+			log.Fatalf("Invalid native histogram bucket factor %v, it must be above 1", *lagNativeFactor)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithNativeLagHistogram(*lagNativeFactor))
+	}
+
+	if len(*clusterLabels) > 0 {
+		labels, err := exporter.ParseClusterLabels(*clusterLabels)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithClusterLabels(labels))
+	}
+
+	if len(*groupRewrites) > 0 {
+		rules, err := exporter.ParseRewriteRules(*groupRewrites)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithGroupRewrite(rules, *rawGroupLabel))
+	}
+
+	if len(*topicRewrites) > 0 || len(*topicTenants) > 0 {
+		rules, err := exporter.ParseRewriteRules(*topicRewrites)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tenantRules, err := exporter.ParseRewriteRules(*topicTenants)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithTopicRewrite(rules, tenantRules))
+	}
+
+	topicFilter := exporter.TopicFilter{ExcludeInternal: *excludeInternal}
+
+	if *topicInclude != "" {
+		re, err := exporter.AnchoredRegexp(*topicInclude)
+		if err != nil {
+			log.Fatalf("Invalid topic include regex: %v", err)
+		}
+		topicFilter.Include = re
+	}
+
+	if *topicExclude != "" {
+		re, err := exporter.AnchoredRegexp(*topicExclude)
+		if err != nil {
+			log.Fatalf("Invalid topic exclude regex: %v", err)
+		}
+		topicFilter.Exclude = re
+	}
+
+	collectorOpts = append(collectorOpts, exporter.WithTopicFilter(topicFilter))
+
+	// The probes shape their metrics like the scrapes', but don't keep state
+	// across them.
+	probeOpts := append([]exporter.CollectorOption(nil), collectorOpts...)
+
+	if *refreshInterval > 0 || len(*clusterRefresh) > 0 {
+		intervals, err := exporter.ParseRefreshIntervals(*refreshInterval, *clusterRefresh)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithRefreshIntervals(intervals))
+	}
+
+	if *incrementalRefresh > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithIncrementalRefresh(*incrementalRefresh, *maxIdleGroupAge))
+	}
+
+	if *adaptiveRefresh > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithAdaptiveRefresh(*adaptiveRefresh))
+	}
+
+	if *leaseFile != "" {
+		identity := *leaseIdentity
+		if identity == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("Failed getting the hostname, set --collector.leader-identity: %v", err)
+			}
+
+			identity = hostname
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithLeaseLock(&exporter.LeaseLock{
+			Path:     *leaseFile,
+			Identity: identity,
+			Duration: *leaseDuration,
+		}))
+	}
+
+	if *shardCount > 1 {
+		if *shardIndex < 0 || *shardIndex >= *shardCount {
+			log.Fatalf("Invalid shard index %v, expected 0 to %v", *shardIndex, *shardCount-1)
+		}
+
+		collectorOpts = append(collectorOpts, exporter.WithShard(exporter.Shard{Index: *shardIndex, Count: *shardCount}))
+	}
+
+	if *staleGrace > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithStaleGrace(*staleGrace))
+	}
+
+	if *scrapeJitter > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithScrapeJitter(*scrapeJitter))
+	}
+
+	if *filterRulesFile != "" {
+		opt, err := exporter.WithFilterRulesFile(*filterRulesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		collectorOpts = append(collectorOpts, opt)
+	}
+
+	if *memoryBudget > 0 {
+		collectorOpts = append(collectorOpts, exporter.WithMemoryBudget(uint64(*memoryBudget)))
+	}
+
+	if *snapshotDir != "" {
+		opt, err := exporter.WithSnapshotDir(*snapshotDir)
+		if err != nil {
+			log.Fatalf("Failed restoring the snapshots: %v", err)
+		}
+
+		collectorOpts = append(collectorOpts, opt)
+	}
 
 	c := exporter.NewCollector(
-		*burrowAddress,
-		*burrowAPIVersion,
+		client,
 		*collectorDisabledMetrics,
+		collectorOpts...,
 	)
 
-	prometheus.MustRegister(c)
+	if command == benchCommand.FullCommand() {
+		runBench(c, *benchRefreshes)
+		return
+	}
+
+	var web *webConfig
+	if *webConfigFile != "" {
+		var err error
+		if web, err = loadWebConfig(*webConfigFile); err != nil {
+			log.Fatalf("Failed loading the web config: %v", err)
+		}
+	}
+
+	var servers []*webServer
+	if *systemdSocket {
+		listeners, err := systemdListeners()
+		if err != nil {
+			log.Fatalf("Failed using systemd socket activation: %v", err)
+		}
+
+		servers = append(servers, newWebServer(listeners, web, *accessLogEnabled))
+	} else {
+		for _, value := range *listenAddresses {
+			address, configFile := splitListenAddress(value)
+
+			config := web
+			if configFile != "" {
+				var err error
+				if config, err = loadWebConfig(configFile); err != nil {
+					log.Fatalf("Failed loading the web config of %v: %v", address, err)
+				}
+			}
+
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			servers = append(servers, newWebServer([]net.Listener{listener}, config, *accessLogEnabled))
+		}
+	}
+
+	webConfigs := []*webConfig{web}
+	for _, server := range servers {
+		for _, listener := range server.listeners {
+			log.Infof("Listening on %v", listener.Addr())
+		}
+
+		if server.config != web {
+			webConfigs = append(webConfigs, server.config)
+		}
+	}
+
+	reloader := newConfigReloader(c, webConfigs)
+	prometheus.MustRegister(client, c, reloader)
+
+	if !*runtimeMetrics {
+		prometheus.Unregister(collectors.NewGoCollector())
+		prometheus.Unregister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	http.Handle(*metricsPath, c.ScrapeTimeoutHandler(*scrapeTimeoutOffset, filteredMetricsHandler(prometheus.DefaultGatherer, promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(prometheus.DefaultGatherer, metricsHandlerOpts())))))
+	http.Handle("/healthz", healthzHandler(c, *healthzMaxScrape))
+	http.Handle("/ready", readyHandler(c, *readyRequireBurrow))
+
+	if *enableLifecycle {
+		http.Handle("/-/refresh", refreshHandler(c))
+		http.Handle("/-/reload", reloader.handler())
+	}
+
+	if *probeTargets != "" {
+		targets, err := exporter.AnchoredRegexp(*probeTargets)
+		if err != nil {
+			log.Fatalf("Invalid probe target regex: %v", err)
+		}
+
+		http.Handle("/probe", &prober{
+			targets:         targets,
+			apiVersion:      *burrowAPIVersion,
+			clientOpts:      clientOpts,
+			disabledMetrics: *collectorDisabledMetrics,
+			collectorOpts:   probeOpts,
+			timeoutOffset:   *scrapeTimeoutOffset,
+		})
+	}
+
+	http.Handle("/api/v1/lag", lagHandler(c))
+	http.Handle("/api/v1/problems", problemsHandler(c))
+	http.Handle("/api/v1/events", eventsHandler(c))
+	http.Handle("/api/v1/watch", watchHandler(c))
+	var burrowURLs []string
+	for _, address := range client.BaseURLs() {
+		burrowURLs = append(burrowURLs, exporter.RedactURL(address))
+	}
+
+	http.Handle("/", landingPage(landingPageData{
+		Version:         version.Info(),
+		BuildContext:    version.BuildContext(),
+		BurrowURLs:      burrowURLs,
+		RefreshInterval: *refreshInterval,
+		ClusterRefresh:  *clusterRefresh,
+		MetricsPath:     *metricsPath,
+		Probe:           *probeTargets != "",
+		Lifecycle:       *enableLifecycle,
+	}))
+
+	for _, server := range servers {
+		// End the event streams, which would otherwise hold the shutdown.
+		server.RegisterOnShutdown(c.CloseSubscriptions)
+
+		go func(server *webServer) {
+			if err := server.serve(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}(server)
+	}
+
+	// The pushes and writes of the metrics to the other systems stop on
+	// shutdown.
+	exportCtx, stopExports := context.WithCancel(context.Background())
+	var exports sync.WaitGroup
+
+	if *pushGatewayURL != "" {
+		pusher := newPusher(*pushGatewayURL, *pushJob, prometheus.DefaultGatherer, c)
+
+		exports.Add(1)
+		go func() {
+			defer exports.Done()
+			pusher.run(exportCtx, *pushInterval)
+		}()
+	}
+
+	if *graphiteAddress != "" {
+		bridge, err := graphite.NewBridge(&graphite.Config{
+			URL:           *graphiteAddress,
+			Prefix:        *graphitePrefix,
+			Interval:      *graphiteInterval,
+			Gatherer:      prometheus.DefaultGatherer,
+			Logger:        log.NewErrorLogger(),
+			ErrorHandling: graphite.ContinueOnError,
+		})
+		if err != nil {
+			log.Fatalf("Failed setting up graphite: %v", err)
+		}
+
+		exports.Add(1)
+		go func() {
+			defer exports.Done()
+			bridge.Run(exportCtx)
+		}()
+	}
+
+	if *influxURL != "" {
+		writer, err := newInfluxWriter(influxConfig{
+			URL:        *influxURL,
+			APIVersion: *influxAPIVersion,
+			Database:   *influxDatabase,
+			Retention:  *influxRetention,
+			Username:   *influxUsername,
+			Password:   *influxPassword,
+			Org:        *influxOrg,
+			Bucket:     *influxBucket,
+			Token:      *influxToken,
+		}, prometheus.DefaultGatherer)
+		if err != nil {
+			log.Fatalf("Failed setting up InfluxDB: %v", err)
+		}
+
+		exports.Add(1)
+		go func() {
+			defer exports.Done()
+			writer.run(exportCtx, *influxInterval)
+		}()
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcListenAddress != "" {
+		var err error
+		if grpcServer, err = newGRPCServer(c, web); err != nil {
+			log.Fatalf("Failed setting up the gRPC server: %v", err)
+		}
+
+		go func() {
+			if err := serveGRPC(grpcServer, *grpcListenAddress); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	go func() {
+		for range reloads {
+			reloader.reload()
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	log.Infof("Received %v, shutting down", <-signals)
+
+	// Cancel the requests to burrow, so the in-flight scrapes finish with
+	// what they have rather than holding the shutdown.
+	client.Close()
+	stopExports()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	if grpcServer != nil {
+		// Streams only end with the shutdown, so they're cut by Stop.
+		grpcServer.Stop()
+	}
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *webServer) {
+			defer wg.Done()
+
+			if err := server.Shutdown(ctx); err != nil {
+				log.With("err", err).Error("Failed serving the in-flight requests")
+			}
+		}(server)
+	}
 
-	http.Handle(*metricsPath, promhttp.Handler())
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>Burrow Exporter</title></head>
-			<body>
-			<h1>Burrow Exporter</h1>
-			<p><a href="` + *metricsPath + `">Metrics</a></p>
-			</body>
-			</html>`))
-	})
+	wg.Wait()
+	exports.Wait()
 
-	log.Fatal(http.ListenAndServe(*listenAddress, nil))
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			log.With("err", err).Error("Failed sending the last traces")
+		}
+	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// prober scrapes the burrow of each request on demand, like the blackbox
+// exporter, so one exporter serves many burrows picked by prometheus'
+// relabeling.
+type prober struct {
+	// targets are the allowed targets, as the exporter requests them.
+	targets         *regexp.Regexp
+	apiVersion      int
+	clientOpts      []exporter.ClientOption
+	disabledMetrics string
+	collectorOpts   []exporter.CollectorOption
+	timeoutOffset   time.Duration
+}
+
+// ServeHTTP scrapes the burrow of the target query param, e.g.
+// http://burrow:8000 or burrow:8000, optionally only the clusters in the
+// cluster query params.
+func (p *prober) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "The target param is required", http.StatusBadRequest)
+		return
+	}
+
+	if !p.targets.MatchString(target) {
+		http.Error(w, "The target isn't allowed: "+target, http.StatusForbidden)
+		return
+	}
+
+	address, err := url.Parse(target)
+	if err != nil || address.Host == "" {
+		// Default to http, like the --burrow.address flag.
+		address, err = url.Parse("http://" + target)
+	}
+
+	if err != nil || (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		http.Error(w, "Invalid target: "+target, http.StatusBadRequest)
+		return
+	}
+
+	client := exporter.NewBurrowClient([]string{address.String()}, p.apiVersion, p.clientOpts...)
+	defer client.Close()
+
+	opts := p.collectorOpts
+	if clusters := r.URL.Query()["cluster"]; len(clusters) > 0 {
+		opts = append(opts[:len(opts):len(opts)], exporter.WithClusters(clusters))
+	}
+
+	c := exporter.NewCollector(client, p.disabledMetrics, opts...)
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(uncheckedCollector{c}); err != nil {
+		log.With("err", err).Errorf("Failed probing %v", target)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	handler := promhttp.HandlerFor(registry, metricsHandlerOpts())
+	c.ScrapeTimeoutHandler(p.timeoutOffset, handler).ServeHTTP(w, r)
+}
+
+// uncheckedCollector describes no metrics, registering the collector as
+// unchecked, as describing it scrapes burrow.
+type uncheckedCollector struct {
+	prometheus.Collector
+}
+
+func (uncheckedCollector) Describe(chan<- *prometheus.Desc) {}
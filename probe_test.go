@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+func TestProber(t *testing.T) {
+	mock := burrowtest.NewServer(burrowtest.Synthetic(2, 1, 1))
+	defer mock.Close()
+
+	host := strings.TrimPrefix(mock.URL, "http://")
+
+	targets, err := exporter.AnchoredRegexp(`(\w+://)?` + regexp.QuoteMeta(host))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(&prober{targets: targets, apiVersion: 3})
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		status int
+		// clusters are the clusters whose lag is exported.
+		clusters []string
+	}{
+		{name: "no target", status: http.StatusBadRequest},
+		{name: "target not allowed", query: url.Values{"target": {"burrow:8000"}}, status: http.StatusForbidden},
+		{name: "invalid scheme", query: url.Values{"target": {"ftp://" + host}}, status: http.StatusBadRequest},
+		{name: "url", query: url.Values{"target": {mock.URL}}, status: http.StatusOK, clusters: []string{"cluster-0", "cluster-1"}},
+		{name: "address", query: url.Values{"target": {host}}, status: http.StatusOK, clusters: []string{"cluster-0", "cluster-1"}},
+		{name: "cluster", query: url.Values{"target": {host}, "cluster": {"cluster-1"}}, status: http.StatusOK, clusters: []string{"cluster-1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/probe?" + test.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, cluster := range []string{"cluster-0", "cluster-1"} {
+				lag := `kafka_burrow_total_lag{cluster="` + cluster + `",group="group-0"}`
+				if exported, want := strings.Contains(string(body), lag), oneOf(test.clusters, cluster); exported != want {
+					t.Errorf("got the lag of %v exported %v, want %v", cluster, exported, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// pusher pushes the metrics to a pushgateway, for when prometheus can't
+// reach the exporter. The metrics of each cluster are pushed as the group of
+// its cluster label once refreshed, and the others as the group of the job.
+type pusher struct {
+	url       string
+	job       string
+	client    *http.Client
+	gatherer  prometheus.Gatherer
+	collector *exporter.Collector
+
+	// pushed are the scrape times of the clusters' pushed metrics.
+	pushed map[string]time.Time
+}
+
+func newPusher(gatewayURL, job string, gatherer prometheus.Gatherer, collector *exporter.Collector) *pusher {
+	return &pusher{
+		url:       gatewayURL,
+		job:       job,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		gatherer:  gatherer,
+		collector: collector,
+		pushed:    make(map[string]time.Time),
+	}
+}
+
+// run gathers the metrics, scraping burrow when due, and pushes them every
+// interval, until ctx is done.
+func (p *pusher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.push(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *pusher) push(ctx context.Context) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		log.With("err", err).Warn("Failed gathering some of the metrics to push")
+	}
+
+	groups := groupByCluster(families)
+
+	for _, cluster := range p.collector.Lag() {
+		if !cluster.ScrapedAt.After(p.pushed[cluster.Cluster]) {
+			continue
+		}
+
+		if err := p.pushGroup(ctx, cluster.Cluster, groups[cluster.Cluster]); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.With("err", err).Errorf("Failed pushing the metrics of cluster %v", cluster.Cluster)
+			continue
+		}
+
+		p.pushed[cluster.Cluster] = cluster.ScrapedAt
+	}
+
+	if err := p.pushGroup(ctx, "", groups[""]); err != nil && ctx.Err() == nil {
+		log.With("err", err).Error("Failed pushing the exporter's metrics")
+	}
+}
+
+// pushGroup replaces the metrics of the cluster's group, or of the job's
+// when cluster is empty.
+func (p *pusher) pushGroup(ctx context.Context, cluster string, families []*dto.MetricFamily) error {
+	pusher := push.New(p.url, p.job).Client(p.client).Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return families, nil
+	}))
+
+	if cluster != "" {
+		pusher = pusher.Grouping("cluster", cluster)
+	}
+
+	return pusher.PushContext(ctx)
+}
+
+// groupByCluster splits the metrics by their cluster label, which is left
+// out as the grouping key adds it back, the metrics without it are grouped
+// under "".
+func groupByCluster(families []*dto.MetricFamily) map[string][]*dto.MetricFamily {
+	groups := make(map[string][]*dto.MetricFamily)
+
+	for _, family := range families {
+		byCluster := make(map[string]*dto.MetricFamily)
+		var clusters []string
+
+		for _, metric := range family.Metric {
+			cluster := ""
+			labels := metric.Label[:0:0]
+			for _, label := range metric.Label {
+				if label.GetName() == "cluster" {
+					cluster = label.GetValue()
+				} else {
+					labels = append(labels, label)
+				}
+			}
+
+			grouped, ok := byCluster[cluster]
+			if !ok {
+				grouped = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+				byCluster[cluster] = grouped
+				clusters = append(clusters, cluster)
+			}
+
+			metric.Label = labels
+			grouped.Metric = append(grouped.Metric, metric)
+		}
+
+		for _, cluster := range clusters {
+			groups[cluster] = append(groups[cluster], byCluster[cluster])
+		}
+	}
+
+	return groups
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestGroupByCluster(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	lag := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "lag", Help: "Lag."}, []string{"cluster", "group"})
+	lag.WithLabelValues("local", "orders").Set(1)
+	lag.WithLabelValues("local", "payments").Set(2)
+	lag.WithLabelValues("remote", "orders").Set(3)
+
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "up", Help: "Up."})
+	up.Set(1)
+
+	registry.MustRegister(lag, up)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The series of each group, as the family name and its labels.
+	want := map[string][]string{
+		"":       {"up"},
+		"local":  {"lag group=orders", "lag group=payments"},
+		"remote": {"lag group=orders"},
+	}
+
+	got := make(map[string][]string)
+	for cluster, families := range groupByCluster(families) {
+		for _, family := range families {
+			for _, metric := range family.Metric {
+				series := family.GetName()
+				for _, label := range metric.Label {
+					series += " " + label.GetName() + "=" + label.GetValue()
+				}
+
+				got[cluster] = append(got[cluster], series)
+			}
+		}
+
+		sort.Strings(got[cluster])
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// configReloader reloads the configuration files, i.e. the filter rules and
+// the web configs, the flags only apply on restart.
+type configReloader struct {
+	collector *exporter.Collector
+	// webConfigs are nil when not set.
+	webConfigs []*webConfig
+
+	success   prometheus.Gauge
+	timestamp prometheus.Gauge
+}
+
+func newConfigReloader(collector *exporter.Collector, webConfigs []*webConfig) *configReloader {
+	r := &configReloader{
+		collector:  collector,
+		webConfigs: webConfigs,
+		success: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "burrow_exporter_config_last_reload_successful",
+			Help: "Whether the last configuration reload succeeded (1) or not (0).",
+		}),
+		timestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "burrow_exporter_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload.",
+		}),
+	}
+
+	// The configuration loaded on startup counts as the first reload.
+	r.success.Set(1)
+	r.timestamp.Set(float64(time.Now().Unix()))
+
+	return r
+}
+
+func (r *configReloader) reload() error {
+	err := r.collector.ReloadRules()
+	for _, web := range r.webConfigs {
+		if err == nil && web != nil {
+			err = web.reload()
+		}
+	}
+
+	if err != nil {
+		log.With("err", err).Error("Failed reloading the configuration, keeping the previous one")
+		r.success.Set(0)
+		return err
+	}
+
+	log.Info("Reloaded the configuration")
+	r.success.Set(1)
+	r.timestamp.Set(float64(time.Now().Unix()))
+
+	return nil
+}
+
+// handler reloads the configuration on POST requests.
+func (r *configReloader) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := r.reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte("Reloaded"))
+	})
+}
+
+func (r *configReloader) Describe(ch chan<- *prometheus.Desc) {
+	r.success.Describe(ch)
+	r.timestamp.Describe(ch)
+}
+
+func (r *configReloader) Collect(ch chan<- prometheus.Metric) {
+	r.success.Collect(ch)
+	r.timestamp.Collect(ch)
+}
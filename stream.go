@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+)
+
+// eventsKeepAlive is how often a comment is sent on an idle event stream,
+// so proxies don't time it out.
+const eventsKeepAlive = 30 * time.Second
+
+// eventsHandler streams an event per consumer group of each refreshed
+// cluster as server-sent events, optionally of the clusters and groups in
+// the cluster and group query params.
+func eventsHandler(c *exporter.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming isn't supported", http.StatusInternalServerError)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		clusters, groups := r.Form["cluster"], r.Form["group"]
+
+		sub := c.Subscribe()
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case update, ok := <-sub.Updates:
+				if !ok {
+					return
+				}
+
+				if !oneOf(clusters, update.Cluster) || !oneOf(groups, update.Group) {
+					continue
+				}
+
+				data, err := json.Marshal(update)
+				if err != nil {
+					continue
+				}
+
+				if _, err := fmt.Fprintf(w, "event: group\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+
+			flusher.Flush()
+		}
+	})
+}
+
+// watchEvent is a change of a consumer group pushed to the websocket
+// clients, either of its status or of its total lag crossing the threshold
+// (lag-above, lag-below).
+type watchEvent struct {
+	Type      string    `json:"type"`
+	Cluster   string    `json:"cluster"`
+	Group     string    `json:"group"`
+	Previous  string    `json:"previous,omitempty"`
+	Status    string    `json:"status"`
+	TotalLag  int64     `json:"totallag"`
+	Threshold int64     `json:"threshold,omitempty"`
+	ScrapedAt time.Time `json:"scraped_at"`
+}
+
+// watchHandler pushes the status changes of the consumer groups over a
+// websocket, as of the latest scrape when connecting, and their total lag
+// crossing the lag-threshold query param when given. The clusters and
+// groups can be narrowed down with the cluster and group query params.
+func watchHandler(c *exporter.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		clusters, groups := r.Form["cluster"], r.Form["group"]
+
+		var threshold int64
+		checkLag := r.Form.Get("lag-threshold") != ""
+		if checkLag {
+			var err error
+			if threshold, err = strconv.ParseInt(r.Form.Get("lag-threshold"), 10, 64); err != nil {
+				http.Error(w, "Invalid lag-threshold: "+r.Form.Get("lag-threshold"), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Subscribe before taking the current lag, so no update is missed
+		// in between.
+		sub := c.Subscribe()
+		defer sub.Close()
+
+		ws, err := upgradeWebsocket(w, r)
+		if err != nil {
+			log.With("err", err).Debug("Failed upgrading to a websocket")
+			return
+		}
+		defer ws.Close()
+
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			if err := ws.serveControl(); err != nil && err != io.EOF {
+				log.With("err", err).Debug("Failed reading from the websocket")
+			}
+		}()
+
+		current := make(map[exporter.GroupKey]exporter.GroupUpdate)
+		for _, cluster := range c.Lag() {
+			for _, group := range cluster.Groups {
+				key := exporter.GroupKey{Cluster: cluster.Cluster, Group: group.Group}
+				current[key] = exporter.GroupUpdate{Cluster: cluster.Cluster, Group: group.Group, Status: group.Status, TotalLag: group.TotalLag}
+			}
+		}
+
+		for {
+			var update exporter.GroupUpdate
+			var ok bool
+
+			select {
+			case update, ok = <-sub.Updates:
+				if !ok {
+					return
+				}
+			case <-closed:
+				return
+			}
+
+			if !oneOf(clusters, update.Cluster) || !oneOf(groups, update.Group) {
+				continue
+			}
+
+			key := exporter.GroupKey{Cluster: update.Cluster, Group: update.Group}
+			previous, seen := current[key]
+			current[key] = update
+
+			if !seen {
+				continue
+			}
+
+			event := watchEvent{
+				Cluster:   update.Cluster,
+				Group:     update.Group,
+				Status:    update.Status,
+				TotalLag:  update.TotalLag,
+				ScrapedAt: update.ScrapedAt,
+			}
+
+			var events []watchEvent
+			if update.Status != previous.Status {
+				event.Type, event.Previous = "status", previous.Status
+				events = append(events, event)
+			}
+
+			if checkLag && (previous.TotalLag > threshold) != (update.TotalLag > threshold) {
+				event.Type, event.Previous, event.Threshold = "lag-below", "", threshold
+				if update.TotalLag > threshold {
+					event.Type = "lag-above"
+				}
+				events = append(events, event)
+			}
+
+			for _, event := range events {
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+
+				if err := ws.WriteText(data); err != nil {
+					log.With("err", err).Debug("Failed writing to the websocket")
+					return
+				}
+			}
+		}
+	})
+}
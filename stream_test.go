@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/exporter/burrowtest"
+)
+
+func TestEventsHandler(t *testing.T) {
+	c := scrapedCollector(t, burrowtest.Synthetic(2, 2, 1))
+
+	server := httptest.NewServer(eventsHandler(c))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		groups []string
+	}{
+		{name: "all", groups: []string{"cluster-0/group-0", "cluster-0/group-1", "cluster-1/group-0", "cluster-1/group-1"}},
+		{name: "cluster", query: "?cluster=cluster-1", groups: []string{"cluster-1/group-0", "cluster-1/group-1"}},
+		{name: "group", query: "?group=group-1", groups: []string{"cluster-0/group-1", "cluster-1/group-1"}},
+		{name: "cluster and group", query: "?cluster=cluster-0&group=group-0", groups: []string{"cluster-0/group-0"}},
+		{name: "unknown group", query: "?group=other"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("got content type %v, want text/event-stream", contentType)
+			}
+
+			// The stream is subscribed once its headers are sent, the updates
+			// of the following scrape are streamed, then it's disconnected.
+			collect(c)
+			time.AfterFunc(500*time.Millisecond, cancel)
+
+			var groups []string
+			scanner := bufio.NewScanner(resp.Body)
+
+			for scanner.Scan() {
+				line := scanner.Text()
+
+				switch {
+				case line == "event: group", line == "":
+				case strings.HasPrefix(line, "data: "):
+					var update exporter.GroupUpdate
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &update); err != nil {
+						t.Fatal(err)
+					}
+
+					groups = append(groups, update.Cluster+"/"+update.Group)
+				default:
+					t.Errorf("unexpected line %q", line)
+				}
+			}
+
+			sort.Strings(groups)
+			if strings.Join(groups, ",") != strings.Join(test.groups, ",") {
+				t.Errorf("got the events of %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shamil/burrow_exporter/exporter"
+	"github.com/shamil/burrow_exporter/internal/log"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+)
+
+// webConfig is the web config file, in the format of the prometheus
+// exporter toolkit.
+type webConfig struct {
+	TLSServerConfig *tlsServerConfig `yaml:"tls_server_config"`
+	// BasicAuthUsers maps the users to their bcrypt hashed passwords.
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users"`
+
+	path string
+	// mutex guards BasicAuthUsers, which are replaced on reload, and
+	// authCache.
+	mutex sync.Mutex
+	// authCache keeps the successful logins, keyed by authCacheKey, so
+	// bcrypt isn't run on every request.
+	authCache map[string]bool
+}
+
+const (
+	// dummyHash is compared to the password of unknown users, so they take
+	// as long to be rejected as the known ones with a wrong password.
+	dummyHash = "$2y$10$QOauhQNbBCuQDKes6eFzPeMqBSjb7Mr5DUmpZ/VcEd00UAV/LDeSi"
+
+	// authCacheSize bounds the cached logins, the cache is emptied once full.
+	authCacheSize = 100
+)
+
+type tlsServerConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+func loadWebConfig(path string) (*webConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &webConfig{path: path}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("parsing %v: %v", path, err)
+	}
+
+	for user, hash := range config.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash of user %v: %v", user, err)
+		}
+	}
+
+	return config, nil
+}
+
+// reload reloads the basic auth users, the TLS settings are only read on
+// startup, while the certificate files are read on each handshake.
+func (c *webConfig) reload() error {
+	config, err := loadWebConfig(c.path)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.BasicAuthUsers = config.BasicAuthUsers
+	c.authCache = nil
+
+	return nil
+}
+
+// checkPassword tells whether the password is the user's, and whether basic
+// auth is required at all.
+func (c *webConfig) checkPassword(user, password string) (ok bool, required bool) {
+	c.mutex.Lock()
+	hash, found := c.BasicAuthUsers[user]
+	required = len(c.BasicAuthUsers) > 0
+	c.mutex.Unlock()
+
+	if !required {
+		return true, false
+	}
+
+	if !found {
+		hash = dummyHash
+	}
+
+	key := authCacheKey(user, hash, password)
+
+	c.mutex.Lock()
+	cached := c.authCache[key]
+	c.mutex.Unlock()
+
+	if cached {
+		return true, true
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if !found || err != nil {
+		return false, true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.authCache) >= authCacheSize {
+		c.authCache = nil
+	}
+	if c.authCache == nil {
+		c.authCache = make(map[string]bool)
+	}
+	c.authCache[key] = true
+
+	return true, true
+}
+
+// authCacheKey hashes the login, rather than keeping the passwords in
+// memory.
+func authCacheKey(user, hash, password string) string {
+	sum := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticate requires basic auth for all the requests, when users are
+// configured.
+func (c *webConfig) authenticate(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if ok, _ := c.checkPassword(user, password); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Basic realm="burrow_exporter"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// tlsConfig returns the TLS configuration of the listener, nil when it
+// serves plain HTTP.
+func (c *webConfig) tlsConfig() (*tls.Config, error) {
+	if c == nil || c.TLSServerConfig == nil {
+		return nil, nil
+	}
+
+	tc := c.TLSServerConfig
+	if tc.CertFile == "" || tc.KeyFile == "" {
+		return nil, fmt.Errorf("both cert_file and key_file are required")
+	}
+
+	clientAuth, ok := clientAuthTypes[tc.ClientAuthType]
+	if !ok {
+		return nil, fmt.Errorf("invalid client_auth_type %q", tc.ClientAuthType)
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: clientAuth,
+		// Load the certificate on each handshake, so a renewed one is used
+		// without restarting.
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+			if err != nil {
+				return nil, err
+			}
+
+			return &cert, nil
+		},
+	}
+
+	if tc.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in client_ca_file %v", tc.ClientCAFile)
+		}
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("client_ca_file is required to verify client certificates")
+	}
+
+	// Fail on startup rather than on the first handshake.
+	if _, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// webServer serves the web interface on listeners sharing a web config.
+type webServer struct {
+	*http.Server
+	listeners []net.Listener
+	config    *webConfig
+}
+
+// newWebServer returns the server of the default mux, logging the requests
+// with logRequests, the rejected ones included.
+func newWebServer(listeners []net.Listener, config *webConfig, logRequests bool) *webServer {
+	handler := config.authenticate(http.DefaultServeMux)
+	if logRequests {
+		handler = accessLog(handler)
+	}
+
+	return &webServer{
+		Server:    &http.Server{Handler: handler},
+		listeners: listeners,
+		config:    config,
+	}
+}
+
+// splitListenAddress splits the listen address from its web config file,
+// given as <address>=<file>.
+func splitListenAddress(value string) (address, configFile string) {
+	if i := strings.Index(value, "="); i >= 0 {
+		return value[:i], value[i+1:]
+	}
+
+	return value, ""
+}
+
+// serve serves on the listeners, over TLS when configured, plain HTTP
+// otherwise, until one of them fails.
+func (s *webServer) serve() error {
+	tlsConfig, err := s.config.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	s.TLSConfig = tlsConfig
+
+	errs := make(chan error, len(s.listeners))
+	for _, listener := range s.listeners {
+		go func(listener net.Listener) {
+			if tlsConfig == nil {
+				errs <- s.Serve(listener)
+			} else {
+				errs <- s.ServeTLS(listener, "", "")
+			}
+		}(listener)
+	}
+
+	return <-errs
+}
+
+var landingPageTemplate = template.Must(template.New("landing").Parse(`<html>
+	<head><title>Burrow Exporter</title></head>
+	<body>
+	<h1>Burrow Exporter</h1>
+	<p>Version {{ .Version }}<br>Build {{ .BuildContext }}</p>
+	<h2>Burrow</h2>
+	<ul>
+	{{- range .BurrowURLs }}
+	<li>{{ . }}</li>
+	{{- end }}
+	</ul>
+	<p>Refresh interval: {{ if .RefreshInterval }}{{ .RefreshInterval }}{{ else }}on every scrape{{ end }}
+	{{- range .ClusterRefresh }}, {{ . }}{{ end }}</p>
+	<ul>
+	<li><a href="{{ .MetricsPath }}">Metrics</a>, optionally narrowed down by the collect[] (cluster, group, topic, partition or exporter), cluster and group params</li>
+	<li><a href="/healthz">Liveness</a></li>
+	<li><a href="/ready">Readiness</a></li>
+	<li><a href="/api/v1/lag">Lag</a> as JSON, filtered by the cluster, group, topic and status params</li>
+	<li><a href="/api/v1/problems">Problems</a>, the groups in WARN, ERR or STALL, as JSON</li>
+	<li><a href="/api/v1/events">Events</a>, the lag of the refreshed groups as server-sent events</li>
+	<li>/api/v1/watch[?lag-threshold=&lt;lag&gt;] pushes the status changes of the groups, and their lag crossing the threshold, over a websocket</li>
+	{{- if .Probe }}
+	<li>/probe?target=&lt;burrow address&gt;[&amp;cluster=&lt;cluster&gt;] scrapes another burrow on demand</li>
+	{{- end }}
+	{{- if .Lifecycle }}
+	<li>POST /-/reload reloads the filter rules and web config files</li>
+	<li>POST /-/refresh[?cluster=&lt;cluster&gt;[&amp;group=&lt;group&gt;]] refreshes burrow's data right away</li>
+	{{- end }}
+	</ul>
+	</body>
+	</html>
+`))
+
+type landingPageData struct {
+	Version         string
+	BuildContext    string
+	BurrowURLs      []string
+	RefreshInterval time.Duration
+	ClusterRefresh  []string
+	MetricsPath     string
+	Probe           bool
+	Lifecycle       bool
+}
+
+// landingPage serves the page at the root, any other path isn't found.
+func landingPage(data landingPageData) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := landingPageTemplate.Execute(w, data); err != nil {
+			log.With("err", err).Error("Failed rendering the landing page")
+		}
+	})
+}
+
+// healthzHandler reports the exporter alive, regardless of burrow's health,
+// unless a scrape has been running for longer than maxScrape, e.g. as it's
+// stuck.
+func healthzHandler(c *exporter.Collector, maxScrape time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if running := c.ScrapeRunningFor(); running > maxScrape {
+			http.Error(w, fmt.Sprintf("Scraping burrow for %v", running), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("OK"))
+	})
+}
+
+// readyHandler reports the exporter ready once a scrape of burrow
+// succeeded, or with requireBurrow while the last one did, unless it's
+// standing by.
+func readyHandler(c *exporter.Collector, requireBurrow bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Standby() {
+			http.Error(w, "Standing by, another replica holds the leader lease", http.StatusServiceUnavailable)
+			return
+		}
+
+		if !c.Ready(requireBurrow) {
+			http.Error(w, "Burrow wasn't scraped successfully", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("OK"))
+	})
+}
+
+// refreshHandler refreshes burrow's data in the background, optionally only
+// a cluster or a consumer group of it. A single refresh runs at a time, the
+// requests made meanwhile are turned down.
+func refreshHandler(c *exporter.Collector) http.Handler {
+	refreshing := make(chan struct{}, 1)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		cluster, group := r.FormValue("cluster"), r.FormValue("group")
+		if group != "" && cluster == "" {
+			http.Error(w, "The cluster of the group is required", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case refreshing <- struct{}{}:
+		default:
+			http.Error(w, "Already refreshing", http.StatusTooManyRequests)
+			return
+		}
+
+		go func() {
+			defer func() { <-refreshing }()
+			c.Refresh(cluster, group)
+		}()
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Refreshing"))
+	})
+}
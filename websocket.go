@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The subset of RFC 6455 needed to push text messages to the clients, and
+// answer their pings and close.
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooLarge      = 1009
+
+	// maxClientFrame bounds the frames read from the clients, which aren't
+	// expected to send more than control frames.
+	maxClientFrame = 4096
+
+	websocketWriteTimeout = 10 * time.Second
+)
+
+var (
+	errFrameTooLarge = errors.New("websocket frame too large")
+	errClosing       = errors.New("websocket connection closing")
+	errProtocol      = errors.New("websocket protocol error")
+)
+
+// websocketConn is a server side websocket connection, safe for concurrent
+// writes.
+type websocketConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// mutex guards the writes, and closing which is set once the close
+	// frame is sent, as no frame may follow it.
+	mutex   sync.Mutex
+	closing bool
+}
+
+// upgradeWebsocket takes over the connection of a websocket handshake
+// request, responding the failed handshakes itself. The browsers' requests
+// must come from a page of the exporter, so other sites can't read the lag.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	switch {
+	case r.Method != http.MethodGet:
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return nil, errors.New("not a GET request")
+	case !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket"):
+		http.Error(w, "A websocket upgrade is required", http.StatusBadRequest)
+		return nil, errors.New("not a websocket upgrade")
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "Unsupported websocket version", http.StatusBadRequest)
+		return nil, errors.New("unsupported websocket version")
+	case key == "":
+		http.Error(w, "Missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("missing websocket key")
+	case !sameOrigin(r):
+		http.Error(w, "Cross origin websocket requests aren't allowed", http.StatusForbidden)
+		return nil, errors.New("cross origin websocket request")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Websockets aren't supported", http.StatusInternalServerError)
+		return nil, errors.New("connection can't be hijacked")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+
+	conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocketConn{conn: conn, reader: rw.Reader}, nil
+}
+
+// sameOrigin tells whether the request comes from the exporter's host, or
+// from a client other than a browser, which doesn't send the Origin header.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains tells whether the comma separated header has the token,
+// case insensitively.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// writeFrame writes an unfragmented, unmasked frame.
+func (c *websocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closing {
+		return errClosing
+	}
+	c.closing = opcode == opClose
+
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// WriteText sends a text message.
+func (c *websocketConn) WriteText(payload []byte) error {
+	return c.writeFrame(opText, payload)
+}
+
+// readFrame reads a frame of the client, which must be masked, fin telling
+// whether it's the last fragment of its message. The control frames can't
+// be fragmented, and no extension is negotiated.
+func (c *websocketConn) readFrame() (opcode byte, fin bool, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return 0, false, nil, err
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	if header[1]&0x80 == 0 {
+		return 0, false, nil, errors.New("unmasked websocket frame")
+	}
+
+	switch opcode {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return 0, false, nil, errProtocol
+	}
+
+	control := opcode&0x8 != 0
+	if header[0]&0x70 != 0 || (control && (!fin || header[1]&0x7f > 125)) {
+		return 0, false, nil, errProtocol
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, false, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return 0, false, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	if length > maxClientFrame {
+		return 0, false, nil, errFrameTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return 0, false, nil, err
+	}
+
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, false, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, fin, payload, nil
+}
+
+// serveControl answers the pings of the client until it closes the
+// connection, or the connection fails. The messages of the client are
+// ignored, though their fragments must be in order, and the connection is
+// closed with the status of the frames breaking the protocol.
+func (c *websocketConn) serveControl() error {
+	// fragmented is whether the continuation of a message is expected.
+	fragmented := false
+
+	for {
+		opcode, fin, payload, err := c.readFrame()
+		switch err {
+		case nil:
+		case errProtocol:
+			c.writeFrame(opClose, closePayload(closeProtocolError))
+			return err
+		case errFrameTooLarge:
+			c.writeFrame(opClose, closePayload(closeTooLarge))
+			return err
+		default:
+			return err
+		}
+
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return nil
+		case opContinuation, opText, opBinary:
+			// A message starts unless it's continued, and must not start
+			// before the previous one ended.
+			if (opcode == opContinuation) != fragmented {
+				c.writeFrame(opClose, closePayload(closeProtocolError))
+				return errProtocol
+			}
+
+			fragmented = !fin
+		}
+	}
+}
+
+// closePayload returns the payload of a close frame with the status code.
+func closePayload(code uint16) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, code)
+
+	return payload
+}
+
+// Close sends a normal closure and closes the connection.
+func (c *websocketConn) Close() error {
+	c.writeFrame(opClose, closePayload(closeNormal))
+	return c.conn.Close()
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// clientFrame encodes a masked final frame, as sent by the clients.
+func clientFrame(opcode byte, payload []byte) []byte {
+	return clientFragment(0x80|opcode, payload)
+}
+
+// clientFragment encodes a masked frame with the first byte, i.e. the fin
+// bit and opcode.
+func clientFragment(first byte, payload []byte) []byte {
+	frame := []byte{first}
+
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, 0x80|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	return frame
+}
+
+func pipe() (*websocketConn, net.Conn) {
+	server, client := net.Pipe()
+	return &websocketConn{conn: server, reader: bufio.NewReader(server)}, client
+}
+
+func TestWriteFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		header []byte
+	}{
+		{name: "empty", length: 0, header: []byte{0x81, 0}},
+		{name: "short", length: 125, header: []byte{0x81, 125}},
+		{name: "16 bit length", length: 126, header: []byte{0x81, 126, 0, 126}},
+		{name: "largest 16 bit length", length: 0xffff, header: []byte{0x81, 126, 0xff, 0xff}},
+		{name: "64 bit length", length: 0x10000, header: []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, client := pipe()
+			defer client.Close()
+
+			payload := bytes.Repeat([]byte("x"), test.length)
+
+			frames := make(chan []byte, 1)
+			go func() {
+				frame, _ := io.ReadAll(client)
+				frames <- frame
+			}()
+
+			if err := conn.WriteText(payload); err != nil {
+				t.Fatal(err)
+			}
+
+			conn.conn.Close()
+			frame := <-frames
+
+			if len(frame) != len(test.header)+test.length {
+				t.Fatalf("got a frame of %d bytes, want %d", len(frame), len(test.header)+test.length)
+			}
+
+			if header := frame[:len(test.header)]; !bytes.Equal(header, test.header) {
+				t.Errorf("got header %x, want %x", header, test.header)
+			}
+
+			if !bytes.Equal(frame[len(test.header):], payload) {
+				t.Error("got a different payload")
+			}
+		})
+	}
+}
+
+func TestWriteFrameAfterClose(t *testing.T) {
+	conn, client := pipe()
+	defer client.Close()
+
+	go io.Copy(io.Discard, client)
+
+	if err := conn.writeFrame(opClose, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.WriteText([]byte("late")); err != errClosing {
+		t.Errorf("got error %v, want %v", err, errClosing)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		opcode  byte
+		fin     bool
+		payload []byte
+		err     error
+	}{
+		{name: "ping", frame: clientFrame(opPing, []byte("hello")), opcode: opPing, fin: true, payload: []byte("hello")},
+		{name: "empty close", frame: clientFrame(opClose, nil), opcode: opClose, fin: true, payload: []byte{}},
+		{name: "16 bit length", frame: clientFrame(opText, bytes.Repeat([]byte("y"), 300)), opcode: opText, fin: true, payload: bytes.Repeat([]byte("y"), 300)},
+		{name: "first fragment", frame: clientFragment(opText, []byte("he")), opcode: opText, payload: []byte("he")},
+		{name: "last fragment", frame: clientFragment(0x80|opContinuation, []byte("llo")), opcode: opContinuation, fin: true, payload: []byte("llo")},
+		{name: "too large", frame: clientFrame(opText, make([]byte, maxClientFrame+1)), err: errFrameTooLarge},
+		{name: "64 bit length too large", frame: clientFrame(opText, make([]byte, 0x10000)), err: errFrameTooLarge},
+		{name: "fragmented ping", frame: clientFragment(opPing, []byte("hi")), err: errProtocol},
+		{name: "large ping", frame: clientFrame(opPing, make([]byte, 126)), err: errProtocol},
+		{name: "reserved opcode", frame: clientFrame(0x3, nil), err: errProtocol},
+		{name: "reserved bits", frame: clientFragment(0xc0|opText, nil), err: errProtocol},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, client := pipe()
+			defer client.Close()
+
+			go client.Write(test.frame)
+
+			opcode, fin, payload, err := conn.readFrame()
+			if err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+
+			if opcode != test.opcode || fin != test.fin || !bytes.Equal(payload, test.payload) {
+				t.Errorf("got opcode %d, fin %v and payload %q, want %d, %v and %q", opcode, fin, payload, test.opcode, test.fin, test.payload)
+			}
+		})
+	}
+}
+
+func TestReadFrameUnmasked(t *testing.T) {
+	conn, client := pipe()
+	defer client.Close()
+
+	go client.Write([]byte{0x81, 2, 'h', 'i'})
+
+	if _, _, _, err := conn.readFrame(); err == nil {
+		t.Error("read an unmasked frame")
+	}
+}
+
+func TestServeControl(t *testing.T) {
+	conn, client := pipe()
+	defer client.Close()
+
+	errs := make(chan error, 1)
+	go func() { errs <- conn.serveControl() }()
+
+	reader := bufio.NewReader(client)
+
+	client.Write(clientFrame(opPing, []byte("hi")))
+
+	pong := make([]byte, 4)
+	if _, err := io.ReadFull(reader, pong); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []byte{0x80 | opPong, 2, 'h', 'i'}; !bytes.Equal(pong, want) {
+		t.Errorf("got pong %x, want %x", pong, want)
+	}
+
+	client.Write(clientFrame(opClose, []byte{0x03, 0xe8}))
+
+	closing := make([]byte, 4)
+	if _, err := io.ReadFull(reader, closing); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []byte{0x80 | opClose, 2, 0x03, 0xe8}; !bytes.Equal(closing, want) {
+		t.Errorf("got close %x, want %x", closing, want)
+	}
+
+	if err := <-errs; err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+}
+
+func TestServeControlFragments(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		// status is the close status sent, 1000 echoes the client's close.
+		status uint16
+		err    error
+	}{
+		{
+			name: "fragmented message",
+			frames: [][]byte{
+				clientFragment(opText, []byte("he")),
+				clientFrame(opPing, []byte("hi")),
+				clientFragment(opContinuation, []byte("l")),
+				clientFragment(0x80|opContinuation, []byte("lo")),
+				clientFrame(opClose, closePayload(closeNormal)),
+			},
+			status: closeNormal,
+		},
+		{
+			name:   "continuation without a message",
+			frames: [][]byte{clientFragment(0x80|opContinuation, []byte("lo"))},
+			status: closeProtocolError,
+			err:    errProtocol,
+		},
+		{
+			name: "message before the end of the previous one",
+			frames: [][]byte{
+				clientFragment(opText, []byte("he")),
+				clientFrame(opText, []byte("hello")),
+			},
+			status: closeProtocolError,
+			err:    errProtocol,
+		},
+		{
+			name:   "fragmented control frame",
+			frames: [][]byte{clientFragment(opPing, []byte("hi"))},
+			status: closeProtocolError,
+			err:    errProtocol,
+		},
+		{
+			name:   "too large",
+			frames: [][]byte{clientFrame(opText, make([]byte, maxClientFrame+1))},
+			status: closeTooLarge,
+			err:    errFrameTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, client := pipe()
+			defer client.Close()
+
+			errs := make(chan error, 1)
+			go func() { errs <- conn.serveControl() }()
+
+			go func() {
+				for _, frame := range test.frames {
+					client.Write(frame)
+				}
+			}()
+
+			// Skip the pongs, up to the close frame.
+			reader := bufio.NewReader(client)
+			for {
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(reader, header); err != nil {
+					t.Fatal(err)
+				}
+
+				payload := make([]byte, header[1])
+				if _, err := io.ReadFull(reader, payload); err != nil {
+					t.Fatal(err)
+				}
+
+				if header[0]&0x0f != opClose {
+					continue
+				}
+
+				if status := binary.BigEndian.Uint16(payload); status != test.status {
+					t.Errorf("got close status %d, want %d", status, test.status)
+				}
+				break
+			}
+
+			if err := <-errs; err != test.err {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestUpgradeWebsocketOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ws, err := upgradeWebsocket(w, r); err == nil {
+			ws.Close()
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name   string
+		origin string
+		status int
+	}{
+		{name: "no origin", status: http.StatusSwitchingProtocols},
+		{name: "same origin", origin: "http://" + host, status: http.StatusSwitchingProtocols},
+		{name: "other origin", origin: "https://example.com", status: http.StatusForbidden},
+		{name: "other port", origin: "http://" + strings.Split(host, ":")[0] + ":1", status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Sec-WebSocket-Version", "13")
+			req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+		})
+	}
+}